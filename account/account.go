@@ -0,0 +1,210 @@
+package account
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Account is one of the user's accounts, with its balance at the start of the day it began to be tracked
+type Account struct {
+	Name           string
+	OpeningBalance currency.Euro
+	OpeningDate    time.Time
+}
+
+// Assertion states the balance of an account at the end of a day, such as the closing balance of a bank statement
+type Assertion struct {
+	Account string
+	Date    time.Time
+	Balance currency.Euro
+}
+
+// Discrepancy is an assertion which does not match the balance computed from the transactions
+type Discrepancy struct {
+	Assertion
+	Computed currency.Euro
+}
+
+// Difference returns how far the computed balance is from the asserted balance
+func (d Discrepancy) Difference() currency.Euro {
+	return currency.SubtractEuros(d.Computed, d.Balance)
+}
+
+// String describes the asserted and computed balances
+func (d Discrepancy) String() string {
+	return fmt.Sprintf("%s on %s: expected %s, computed %s (difference %s)", d.Account, d.Date.Format(time.DateOnly), d.Balance.String(), d.Computed.String(), d.Difference().String())
+}
+
+// BalancePoint is the balance of an account at the end of a day
+type BalancePoint struct {
+	Date    time.Time
+	Balance currency.Euro
+}
+
+// ReadAccountsFromFile reads account definitions from a CSV file with the columns Name,OpeningBalance,OpeningDate
+func ReadAccountsFromFile(path string) ([]Account, error) {
+	var accounts []Account
+	err := csvfile.Read(path, []string{"Name", "OpeningBalance", "OpeningDate"}, func(row csvfile.Row) error {
+		account := Account{Name: row.Get("Name")}
+		if account.Name == "" {
+			return fmt.Errorf("missing account name")
+		}
+		var err error
+		if account.OpeningBalance, err = row.Amount("OpeningBalance"); err != nil {
+			return err
+		}
+		if account.OpeningDate, err = row.Date("OpeningDate"); err != nil {
+			return err
+		}
+		accounts = append(accounts, account)
+		return nil
+	})
+	return accounts, err
+}
+
+// ReadAssertionsFromFile reads balance assertions from a CSV file with the columns Account,Date,Balance
+func ReadAssertionsFromFile(path string) ([]Assertion, error) {
+	var assertions []Assertion
+	err := csvfile.Read(path, []string{"Account", "Date", "Balance"}, func(row csvfile.Row) error {
+		assertion := Assertion{Account: row.Get("Account")}
+		var err error
+		if assertion.Date, err = row.Date("Date"); err != nil {
+			return err
+		}
+		if assertion.Date.IsZero() {
+			return fmt.Errorf("missing date")
+		}
+		if assertion.Balance, err = row.Amount("Balance"); err != nil {
+			return err
+		}
+		assertions = append(assertions, assertion)
+		return nil
+	})
+	return assertions, err
+}
+
+// BalanceAt returns the balance of the account at the end of a day. Transactions of other accounts, from before the
+// opening date, or whose time is not a date are ignored
+func (a Account) BalanceAt(transactions []transaction.BasicTransaction, date time.Time) currency.Euro {
+	balance := a.OpeningBalance
+	for _, tx := range a.dated(transactions) {
+		if txDate, _ := tx.Date(); !txDate.After(date) {
+			balance = currency.AddEuros(balance, tx.Amount)
+		}
+	}
+	return balance
+}
+
+// RunningBalances returns the balance of the account at the end of each day with transactions, starting with the
+// opening balance on the opening date
+func (a Account) RunningBalances(transactions []transaction.BasicTransaction) []BalancePoint {
+	points := []BalancePoint{{Date: a.OpeningDate, Balance: a.OpeningBalance}}
+	for _, tx := range a.dated(transactions) {
+		date, _ := tx.Date()
+		last := &points[len(points)-1]
+		balance := currency.AddEuros(last.Balance, tx.Amount)
+		if date.Equal(last.Date) {
+			last.Balance = balance
+		} else {
+			points = append(points, BalancePoint{Date: date, Balance: balance})
+		}
+	}
+	return points
+}
+
+// dated returns the account's dated transactions from its opening date onwards, in date order
+func (a Account) dated(transactions []transaction.BasicTransaction) []transaction.BasicTransaction {
+	var dated []transaction.BasicTransaction
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || tx.Account != a.Name || date.Before(a.OpeningDate) {
+			continue
+		}
+		dated = append(dated, tx)
+	}
+	slices.SortStableFunc(dated, func(x, y transaction.BasicTransaction) int {
+		dateX, _ := x.Date()
+		dateY, _ := y.Date()
+		return dateX.Compare(dateY)
+	})
+	return dated
+}
+
+// CheckAssertions compares each assertion with the balance computed from the transactions, returning the assertions
+// which do not match. It returns an error if an assertion is for an unknown account
+func CheckAssertions(accounts []Account, assertions []Assertion, transactions []transaction.BasicTransaction) ([]Discrepancy, error) {
+	var discrepancies []Discrepancy
+	for _, assertion := range assertions {
+		idx := slices.IndexFunc(accounts, func(a Account) bool { return a.Name == assertion.Account })
+		if idx < 0 {
+			return nil, fmt.Errorf("balance assertion for unknown account %q", assertion.Account)
+		}
+		computed := accounts[idx].BalanceAt(transactions, assertion.Date)
+		if computed.Cmp(assertion.Balance) != 0 {
+			discrepancies = append(discrepancies, Discrepancy{Assertion: assertion, Computed: computed})
+		}
+	}
+	return discrepancies, nil
+}
+
+// NetWorthPoint is the balance of every account, and their total, at the end of a day
+type NetWorthPoint struct {
+	Date     time.Time
+	Balances map[string]currency.Euro
+	NetWorth currency.Euro
+}
+
+// NetWorthReport tracks the net worth across all accounts at the end of each month
+type NetWorthReport struct {
+	Accounts []Account
+	Points   []NetWorthPoint
+}
+
+// NewNetWorthReport computes the balances of the accounts at the end of every month from the month of from until the
+// month of to. Accounts count towards the net worth from their opening date
+func NewNetWorthReport(accounts []Account, transactions []transaction.BasicTransaction, from, to time.Time) NetWorthReport {
+	report := NetWorthReport{Accounts: accounts}
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(to); month = month.AddDate(0, 1, 0) {
+		endOfMonth := month.AddDate(0, 1, -1)
+		point := NetWorthPoint{Date: endOfMonth, Balances: make(map[string]currency.Euro)}
+		for _, account := range accounts {
+			if account.OpeningDate.After(endOfMonth) {
+				continue
+			}
+			point.Balances[account.Name] = account.BalanceAt(transactions, endOfMonth)
+			point.NetWorth = currency.AddEuros(point.NetWorth, point.Balances[account.Name])
+		}
+		report.Points = append(report.Points, point)
+	}
+	return report
+}
+
+// String returns a table of the balances of each account and the net worth at the end of each month
+func (r NetWorthReport) String() string {
+	var str strings.Builder
+	str.WriteString("Net Worth Report\n")
+	str.WriteString("Date")
+	for _, account := range r.Accounts {
+		str.WriteString("," + account.Name)
+	}
+	str.WriteString(",Net Worth\n")
+	for _, point := range r.Points {
+		str.WriteString(point.Date.Format(time.DateOnly))
+		for _, account := range r.Accounts {
+			balance, ok := point.Balances[account.Name]
+			if ok {
+				str.WriteString("," + balance.String())
+			} else {
+				str.WriteString(",")
+			}
+		}
+		str.WriteString("," + point.NetWorth.String() + "\n")
+	}
+	return str.String()
+}
@@ -0,0 +1,87 @@
+package account_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readTransactions(t *testing.T, paths ...string) []transaction.BasicTransaction {
+	var transactions []transaction.BasicTransaction
+	for _, path := range paths {
+		payerTransactions, _, err := report.ReadTransactionsFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range payerTransactions {
+			transactions = append(transactions, tx.BasicTransaction)
+		}
+	}
+	return transactions
+}
+
+func TestRunningBalances(t *testing.T) {
+	accounts, err := account.ReadAccountsFromFile("../testdata/accounts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := readTransactions(t, "../testdata/checking.csv", "../testdata/savings.csv")
+	expected := []currency.Euro{currency.NewEuro(4000), currency.NewEuro(3500), currency.NewEuro(2500), currency.NewEuro(2300)}
+	actual := accounts[0].RunningBalances(transactions)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d balances, got %v", len(expected), actual)
+	}
+	for idx := range expected {
+		if actual[idx].Balance.Cmp(expected[idx]) != 0 {
+			t.Errorf("Expected balance %s on %s, got %s", expected[idx].String(), actual[idx].Date.Format(time.DateOnly), actual[idx].Balance.String())
+		}
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	accounts, err := account.ReadAccountsFromFile("../testdata/accounts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertions, err := account.ReadAssertionsFromFile("../testdata/assertions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := readTransactions(t, "../testdata/checking.csv", "../testdata/savings.csv")
+	discrepancies, err := account.CheckAssertions(accounts, assertions, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 1 {
+		t.Fatalf("Expected 1 discrepancy, got %v", discrepancies)
+	}
+	expected := "Savings on 2025-01-31: expected €5500.00, computed €5502.50 (difference €2.50)"
+	if discrepancies[0].String() != expected {
+		t.Errorf("Expected %s, got %s", expected, discrepancies[0].String())
+	}
+	_, err = account.CheckAssertions(accounts, []account.Assertion{{Account: "Brokerage"}}, transactions)
+	if err == nil {
+		t.Errorf("Expected an error for an assertion about an unknown account")
+	}
+}
+
+func TestNetWorthReport(t *testing.T) {
+	accounts, err := account.ReadAccountsFromFile("../testdata/accounts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := readTransactions(t, "../testdata/checking.csv", "../testdata/savings.csv")
+	netWorth := account.NewNetWorthReport(accounts, transactions, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC))
+	expected := `Date,Checking,Savings,Net Worth
+2025-01-31,€2300.00,€5502.50,€7802.50
+2025-02-28,€2300.00,€5502.50,€7802.50
+`
+	if !strings.Contains(netWorth.String(), expected) {
+		t.Errorf("Expected %s, got %s", expected, netWorth.String())
+	}
+}
@@ -0,0 +1,122 @@
+package attachment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// DirName is the name of the attachment store, which by convention is a directory next to the report files
+const DirName = "attachments"
+
+// Store keeps attached files in a directory, named by the SHA-256 hash of their content followed by their original
+// extension, so the same receipt is only stored once and a reference cannot silently point at a changed file
+type Store struct {
+	Dir string
+}
+
+// StoreFor returns the attachment store next to a report file
+func StoreFor(reportPath string) Store {
+	return Store{Dir: filepath.Join(filepath.Dir(reportPath), DirName)}
+}
+
+// Reference returns the name a file is stored under: the hash of its content and its lower-cased extension
+func Reference(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening attachment: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("error reading attachment: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)) + strings.ToLower(filepath.Ext(path)), nil
+}
+
+// Path returns the path of a referenced file in the store
+func (s Store) Path(reference string) string {
+	return filepath.Join(s.Dir, reference)
+}
+
+// Has reports whether a referenced file is in the store
+func (s Store) Has(reference string) bool {
+	_, err := os.Stat(s.Path(reference))
+	return err == nil
+}
+
+// Add copies a file into the store, unless a file with the same content is already there, and returns its reference
+func (s Store) Add(path string) (string, error) {
+	reference, err := Reference(path)
+	if err != nil {
+		return "", err
+	}
+	if s.Has(reference) {
+		return reference, nil
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating attachment store: %w", err)
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening attachment: %w", err)
+	}
+	defer source.Close()
+	// Copy to a temporary file first, so an interrupted copy never leaves a file whose content does not match its name
+	destination, err := os.CreateTemp(s.Dir, "attach-*")
+	if err != nil {
+		return "", fmt.Errorf("error storing attachment: %w", err)
+	}
+	defer os.Remove(destination.Name())
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return "", fmt.Errorf("error storing attachment: %w", err)
+	}
+	if err := destination.Close(); err != nil {
+		return "", fmt.Errorf("error storing attachment: %w", err)
+	}
+	if err := os.Rename(destination.Name(), s.Path(reference)); err != nil {
+		return "", fmt.Errorf("error storing attachment: %w", err)
+	}
+	return reference, nil
+}
+
+// Attach adds a reference to the transaction with an ID, unless it already has it. It returns false if no transaction
+// has the ID
+func Attach(transactions []transaction.PayerTransaction, id, reference string) bool {
+	found := false
+	for i := range transactions {
+		if transactions[i].ID != id {
+			continue
+		}
+		found = true
+		if !slices.Contains(transactions[i].Attachments, reference) {
+			transactions[i].Attachments = append(transactions[i].Attachments, reference)
+		}
+	}
+	return found
+}
+
+// MissingReceipts returns the expenses of at least the threshold, as a positive amount, which have no attachment or
+// whose attachments are all missing from the store. The child lines of split transactions share the receipt of their
+// parent, so only the parent is checked
+func (s Store) MissingReceipts(transactions []transaction.BasicTransaction, threshold currency.Euro) []transaction.BasicTransaction {
+	var missing []transaction.BasicTransaction
+	for _, tx := range transactions {
+		if tx.Parent != "" || !tx.IsIncomeOrExpense() || tx.Amount.Cents() >= 0 || currency.SubtractEuros(currency.NewEuro(0.0), tx.Amount).Cmp(threshold) < 0 {
+			continue
+		}
+		if !slices.ContainsFunc(tx.Attachments, s.Has) {
+			missing = append(missing, tx)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,62 @@
+package attachment_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kevslinger/budget/attachment"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAddStoresFilesByContentHash(t *testing.T) {
+	store := attachment.Store{Dir: filepath.Join(t.TempDir(), attachment.DirName)}
+	reference, err := store.Add(writeFile(t, "Receipt.PDF", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.pdf"; reference != expected {
+		t.Errorf("Expected the reference %s, got %s", expected, reference)
+	}
+	again, err := store.Add(writeFile(t, "copy.pdf", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != reference || len(entries) != 1 {
+		t.Errorf("Expected the same content to be stored once, got %s and %d file(s)", again, len(entries))
+	}
+}
+
+func TestMissingReceipts(t *testing.T) {
+	transactions, _, err := report.ReadTransactionsFromFile("../testdata/receipts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := attachment.Store{Dir: t.TempDir()}
+	reference, err := store.Add(writeFile(t, "monitor.pdf", "monitor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attachment.Attach(transactions, "tx-1", reference) || attachment.Attach(transactions, "tx-9", reference) {
+		t.Error("Expected only existing transactions to be attached")
+	}
+	r := report.NewBudgetReport("receipts", transactions, false).(report.BasicReport)
+	missing := store.MissingReceipts(r.Transactions(), currency.NewEuro(50))
+	// The train ticket references a receipt which is not in the store
+	if len(missing) != 1 || missing[0].ID != "tx-3" {
+		t.Errorf("Expected only the train ticket to lack a receipt, got %v", missing)
+	}
+}
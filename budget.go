@@ -7,12 +7,19 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/kevslinger/budget/classify"
 	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/recurring"
 	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/rules"
 	"github.com/kevslinger/budget/transaction"
 )
 
+// TransferWindowDays is how many days apart the two sides of a transfer between accounts may be recorded
+const TransferWindowDays = 3
+
 func Main() int {
 	fmt.Println("Welcome to the budget tracker app! You may input budget report files as well as individual incomes and/or expenses, which will be compiled into a single report which can be saved to a CSV and/or printed to the screen.")
 
@@ -25,20 +32,61 @@ func Main() int {
 	fmt.Printf("You've selected to record your budget for the period %s.\n", reportName)
 
 	paths := ScanReportPaths(os.Stdout, scanner)
+	var ruleSet rules.RuleSet
+	if rulesPath := ScanRulesPath(os.Stdout, scanner); rulesPath != "" {
+		ruleSet, err = rules.ReadRulesFromFile(rulesPath)
+		if err != nil {
+			fmt.Printf("There was an error reading the rules file with path %s! Continuing without categorisation rules. Error: %v\n", rulesPath, err)
+		}
+	}
 	var reports []report.Report
 	for _, path := range paths {
-		report, err := report.ReadBudgetReportFromFile(reportName, path)
+		report, results, err := ImportReport(reportName, path, ruleSet)
 		if err != nil {
 			fmt.Printf("There was an error reading the budget report file with path %s! Skipping this report. Error: %v", path, err)
 			continue
 		}
+		if len(ruleSet) > 0 {
+			PrintUncategorised(os.Stdout, path, results)
+		}
 		reports = append(reports, report)
 	}
-	combinedReport, err := report.CombineReports(reportName, reports)
+	dedupOptions := report.DefaultDedupOptions()
+	dedupOptions.KeepDuplicates = !ScanRemoveDuplicates(os.Stdout, scanner)
+	combinedReport, duplicates, err := report.CombineReportsWithOptions(reportName, reports, dedupOptions)
 	if err != nil {
 		fmt.Printf("There was an error combining the provided reports together! Please ensure the reports are compatible. Error: %v", err)
 		return 1
 	}
+	PrintDuplicates(os.Stdout, duplicates)
+	combinedReport, transfers, unpaired := report.PairReportTransfers(combinedReport, TransferWindowDays)
+	PrintTransfers(os.Stdout, transfers, unpaired)
+	if recurringPath := ScanRecurringPath(os.Stdout, scanner); recurringPath != "" {
+		definitions, err := recurring.ReadDefinitionsFromFile(recurringPath)
+		if err != nil {
+			fmt.Println("There was an error reading the recurring transactions file! Please restart the program and input a valid file. Error: ", err)
+			return 1
+		}
+		from, to, err := ScanPeriodDates(os.Stdout, scanner)
+		if err != nil {
+			fmt.Println("There was an error reading the dates of your budget period! Please restart the program and input valid dates. Error: ", err)
+			return 1
+		}
+		combinedReport = recurring.AddToReport(combinedReport, definitions, from, to)
+	}
+	if trainingPath := ScanTrainingPath(os.Stdout, scanner); trainingPath != "" {
+		classifier, err := classify.ReadTrainingFile(trainingPath)
+		if err != nil {
+			fmt.Printf("There was an error reading the training file with path %s! Continuing without adding expenses. Error: %v\n", trainingPath, err)
+		} else {
+			expenses, err := ScanExpensesWithSuggestions(os.Stdout, scanner, classifier)
+			if err != nil {
+				fmt.Println("There was an error reading your expenses! Please restart the program and input valid expenses. Error: ", err)
+				return 1
+			}
+			combinedReport = addExpenses(combinedReport, expenses)
+		}
+	}
 	ScanPrintExpenseReport(os.Stdout, scanner, combinedReport)
 	err = ScanSaveExpenseReport(os.Stdout, scanner, combinedReport, reportName)
 	if err != nil {
@@ -76,6 +124,137 @@ func ScanReportPaths(w io.Writer, scanner *bufio.Scanner) []string {
 	return paths
 }
 
+// ScanRulesPath returns the user-inputted path to a CSV file of categorisation rules, or the empty string if they have none
+func ScanRulesPath(w io.Writer, scanner *bufio.Scanner) string {
+	fmt.Fprint(w, "What is the path to your categorisation rules CSV file? Leave empty to skip categorisation: ")
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// ImportReport reads a budget report file, categorising its transactions with the rules before the report is built.
+// It returns which rule, if any, matched each transaction
+func ImportReport(reportName string, path string, ruleSet rules.RuleSet) (report.Report, []rules.Result, error) {
+	transactions, hasPayer, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	categorised, results := ruleSet.Apply(transactions)
+	return report.NewBudgetReport(reportName, categorised, hasPayer), results, nil
+}
+
+// PrintUncategorised lists the transactions from a report file which no categorisation rule matched
+func PrintUncategorised(w io.Writer, path string, results []rules.Result) {
+	uncategorised := rules.Uncategorised(results)
+	if len(uncategorised) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d transaction(s) in %s matched no categorisation rule:\n", len(uncategorised), path)
+	for _, tx := range uncategorised {
+		fmt.Fprintf(w, "%s,%s,%s\n", tx.Time, tx.Amount.String(), tx.Description)
+	}
+}
+
+// ScanRemoveDuplicates asks the user whether transactions appearing in more than one report should only be counted once
+func ScanRemoveDuplicates(w io.Writer, scanner *bufio.Scanner) bool {
+	fmt.Fprintf(w, "Would you like transactions which appear in more than one report to be counted only once? [Y/n] ")
+	y := "y"
+	shouldRemove := y
+	if scanner.Scan() {
+		shouldRemove = scanner.Text()
+		if len(shouldRemove) == 0 {
+			shouldRemove = y
+		}
+	}
+	return strings.ToLower(shouldRemove) == y
+}
+
+// PrintDuplicates lists the duplicate transactions which were dropped while combining reports
+func PrintDuplicates(w io.Writer, duplicates []report.Duplicate) {
+	if len(duplicates) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Dropped %d duplicate transaction(s):\n", len(duplicates))
+	for _, duplicate := range duplicates {
+		fmt.Fprintln(w, duplicate.String())
+	}
+}
+
+// ScanRecurringPath returns the user-inputted path to a CSV file of recurring transactions, or the empty string if they have none
+func ScanRecurringPath(w io.Writer, scanner *bufio.Scanner) string {
+	fmt.Fprint(w, "What is the path to your recurring transactions CSV file? Leave empty to skip recurring transactions: ")
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// ScanTrainingPath returns the user-inputted path to a CSV file of previously categorised transactions, used to suggest
+// categories for expenses entered by hand, or the empty string if they have none
+func ScanTrainingPath(w io.Writer, scanner *bufio.Scanner) string {
+	fmt.Fprint(w, "What is the path to your previously categorised transactions CSV file? Leave empty to skip adding expenses: ")
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// addExpenses returns a copy of the report with the expenses added to its transactions
+func addExpenses(r report.Report, expenses []transaction.BasicTransaction) report.Report {
+	switch r := r.(type) {
+	case report.BasicReport:
+		return report.NewBasicBudgetReport(r.Name, append(r.Transactions(), expenses...))
+	case report.MultiPayerReport:
+		transactions := r.Transactions()
+		for _, expense := range expenses {
+			transactions = append(transactions, transaction.PayerTransaction{BasicTransaction: expense})
+		}
+		return report.NewMultiPayerBudgetReport(r.Name, transactions)
+	}
+	return r
+}
+
+// ScanPeriodDates returns the user-inputted first and last day of the budget period, or an error if one occurred
+func ScanPeriodDates(w io.Writer, scanner *bufio.Scanner) (time.Time, time.Time, error) {
+	fmt.Fprint(w, "Which dates does the period cover? Enter the first and last day, e.g. 2025-01-01 2025-01-31: ")
+	if !scanner.Scan() {
+		return time.Time{}, time.Time{}, fmt.Errorf("no input provided")
+	}
+	dates := strings.Fields(scanner.Text())
+	if len(dates) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected two dates, got %q", scanner.Text())
+	}
+	from, err := transaction.ParseTime(dates[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := transaction.ParseTime(dates[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period ends on %s, before it starts on %s", dates[1], dates[0])
+	}
+	return from, to, nil
+}
+
+// PrintTransfers lists the transfers between accounts which were paired up, and warns about transfers without a counterpart
+func PrintTransfers(w io.Writer, transfers []report.TransferPair, unpaired []transaction.PayerTransaction) {
+	if len(transfers) > 0 {
+		fmt.Fprintf(w, "Found %d transfer(s) between accounts, which are not counted as income or expense:\n", len(transfers))
+		for _, transfer := range transfers {
+			fmt.Fprintln(w, transfer.String())
+		}
+	}
+	if len(unpaired) > 0 {
+		fmt.Fprintf(w, "%d transfer(s) have no matching transfer in another account:\n", len(unpaired))
+		for _, tx := range unpaired {
+			fmt.Fprintf(w, "%s,%s,%s,%s\n", tx.Time, tx.Amount.String(), tx.Description, tx.Account)
+		}
+	}
+}
+
 // ScanIncomes accepts user-submitted information about their income(s) and returns a slice of Income structs
 // or an error, if one occurred
 func ScanIncomes(w io.Writer, scanner *bufio.Scanner) ([]transaction.BasicTransaction, error) {
@@ -114,6 +293,12 @@ func ScanIncomes(w io.Writer, scanner *bufio.Scanner) ([]transaction.BasicTransa
 // ScanExpenses acepts user-submitted data about their expenses, marshals each instance into an Expense object,
 // and returns the slice of Expenses created, or an error if one occurred
 func ScanExpenses(w io.Writer, scanner *bufio.Scanner) ([]transaction.BasicTransaction, error) {
+	return ScanExpensesWithSuggestions(w, scanner, nil)
+}
+
+// ScanExpensesWithSuggestions acts like ScanExpenses, but if a classifier is given it also asks what each expense was for,
+// and pre-fills the category prompt with the classifier's suggestion. Each answer is used to train the classifier further
+func ScanExpensesWithSuggestions(w io.Writer, scanner *bufio.Scanner, classifier *classify.Classifier) ([]transaction.BasicTransaction, error) {
 	var expenses []transaction.BasicTransaction
 	var time string
 	fmt.Fprintf(w, "What time did the %d%s expense occur? Press -1 to stop adding expenses: ", len(expenses)+1, GetNumberEnding(len(expenses)+1))
@@ -123,6 +308,7 @@ func ScanExpenses(w io.Writer, scanner *bufio.Scanner) ([]transaction.BasicTrans
 		return expenses, fmt.Errorf("no time provided for the expense")
 	}
 	var amount float64
+	var description string
 	var category string
 	var err error
 	for time != "-1" {
@@ -136,13 +322,38 @@ func ScanExpenses(w io.Writer, scanner *bufio.Scanner) ([]transaction.BasicTrans
 		} else {
 			return expenses, fmt.Errorf("no amount provided for expense on %s", time)
 		}
-		fmt.Fprintf(w, "To which category does this expense belong? ")
+		var suggestion classify.Suggestion
+		var hasSuggestion bool
+		if classifier != nil {
+			fmt.Fprintf(w, "What was the expense for (e.g. the name of the shop)? ")
+			if scanner.Scan() {
+				description = scanner.Text()
+			} else {
+				return expenses, fmt.Errorf("no description provided for expense on %s of amount %.2f", time, amount)
+			}
+			suggestion, hasSuggestion = classifier.Suggest(description)
+		}
+		if hasSuggestion {
+			fmt.Fprintf(w, "To which category does this expense belong? (Suggested: %s) ", suggestion.String())
+		} else {
+			fmt.Fprintf(w, "To which category does this expense belong? ")
+		}
 		if scanner.Scan() {
 			category = scanner.Text()
+			if len(category) == 0 && hasSuggestion {
+				category = suggestion.Category
+			}
 		} else {
 			return expenses, fmt.Errorf("no category provided for expense on %s of amount %.2f", time, amount)
 		}
-		expenses = append(expenses, transaction.BasicTransaction{Time: time, Amount: currency.NewEuro(-amount), Description: category})
+		if classifier != nil {
+			classifier.Train(description, category)
+		}
+		expense := transaction.BasicTransaction{Time: time, Amount: currency.NewEuro(-amount), Description: category}
+		if classifier != nil {
+			expense.Description, expense.Category = description, category
+		}
+		expenses = append(expenses, expense)
 		fmt.Fprintf(w, "What time did the %d%s expense occur? Press -1 to exit: ", len(expenses)+1, GetNumberEnding(len(expenses)+1))
 		if scanner.Scan() {
 			time = scanner.Text()
@@ -182,7 +393,7 @@ func ScanSaveExpenseReport(w io.Writer, scanner *bufio.Scanner, report report.Re
 		return nil
 	}
 	reportFilename := ScanReportFilename(w, scanner, reportName)
-	return report.Save(reportFilename)
+	return saveReport(report, reportFilename)
 }
 
 func ScanShouldSaveExpenseReport(w io.Writer, scanner *bufio.Scanner) string {
@@ -4,12 +4,18 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kevslinger/budget"
+	"github.com/kevslinger/budget/attachment"
+	"github.com/kevslinger/budget/classify"
 	"github.com/kevslinger/budget/currency"
 	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/rules"
 	"github.com/kevslinger/budget/transaction"
 )
 
@@ -49,6 +55,17 @@ func TestScanReportPaths(t *testing.T) {
 	}
 }
 
+func TestScanRemoveDuplicates(t *testing.T) {
+	inputs := []string{"", "\n", "Y", "n"}
+	expected := []bool{true, true, true, false}
+	for idx, input := range inputs {
+		actual := budget.ScanRemoveDuplicates(new(bytes.Buffer), bufio.NewScanner(strings.NewReader(input)))
+		if actual != expected[idx] {
+			t.Errorf("Expected %t for input %q, got %t", expected[idx], input, actual)
+		}
+	}
+}
+
 func TestScanIncomes(t *testing.T) {
 	firstIncomeTime := "8am"
 	firstIncomeAmount := 100.0
@@ -117,6 +134,33 @@ func TestScanExpenses(t *testing.T) {
 	}
 }
 
+func TestScanExpensesWithSuggestions(t *testing.T) {
+	classifier := classify.NewClassifier()
+	classifier.Train("REWE SAGT DANKE", "Groceries")
+	classifier.Train("Lieferando", "Takeout")
+	expenseScanner := bufio.NewScanner(strings.NewReader("1\n25.50\nREWE\n\n2\n12\nLieferando\nDinner\n3\n4\nBakery\nGroceries\n-1"))
+	w := new(bytes.Buffer)
+	actualExpenses, err := budget.ScanExpensesWithSuggestions(w, expenseScanner, classifier)
+	if err != nil {
+		t.Fatalf("Got error reading expenses: %v", err)
+	}
+	expected := []string{"Groceries", "Dinner", "Groceries"}
+	if len(actualExpenses) != len(expected) {
+		t.Fatalf("Expected %d expenses, got %#v", len(expected), actualExpenses)
+	}
+	for idx := range expected {
+		if actualExpenses[idx].Category != expected[idx] {
+			t.Errorf("Expected category %s, got %s", expected[idx], actualExpenses[idx].Category)
+		}
+	}
+	if !strings.Contains(w.String(), "(Suggested: Groceries (") {
+		t.Errorf("Expected the category prompt to include a suggestion, got %s", w.String())
+	}
+	if suggestion, ok := classifier.Suggest("Bakery"); !ok || suggestion.Category != "Groceries" {
+		t.Errorf("Expected the classifier to learn from the answers, got %v", suggestion)
+	}
+}
+
 func TestScanExpensesErrorsWithEmptyInput(t *testing.T) {
 	_, err := budget.ScanExpenses(new(bytes.Buffer), bufio.NewScanner(strings.NewReader("")))
 	if err == nil {
@@ -209,3 +253,533 @@ func TestGetNumberEnding(t *testing.T) {
 		}
 	}
 }
+
+func TestImportReportAppliesRules(t *testing.T) {
+	ruleSet, err := rules.ReadRulesFromFile("testdata/rules.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, results, err := budget.ImportReport("January", "testdata/bankexport.csv", ruleSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Errorf("Expected 6 results, got %d", len(results))
+	}
+	basicReport, ok := imported.(report.BasicReport)
+	if !ok {
+		t.Fatalf("Expected basic report, got %T", imported)
+	}
+	expected := currency.NewEuro(-25.5)
+	if actual := basicReport.CalculateTotalExpensePerDescription()["Groceries"]; actual.Cmp(expected) != 0 {
+		t.Errorf("Expected %s of groceries, got %s", expected.String(), actual.String())
+	}
+}
+
+func TestRunRulesDryRun(t *testing.T) {
+	w := new(bytes.Buffer)
+	code := budget.Run(w, []string{"rules", "-rules", "testdata/rules.csv", "-dry-run", "testdata/bankexport.csv"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	if !strings.Contains(w.String(), "REWE SAGT DANKE 1234 -> Groceries (rule on line 2") {
+		t.Errorf("Expected the matching rule to be listed, got %s", w.String())
+	}
+	if !strings.Contains(w.String(), "2 of 6 transaction(s) matched no rule") {
+		t.Errorf("Expected the uncategorised count to be listed, got %s", w.String())
+	}
+}
+
+func TestRunRulesPrintsWithoutOutput(t *testing.T) {
+	before, err := os.ReadFile("testdata/bankexport.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	code := budget.Run(w, []string{"rules", "-rules", "testdata/rules.csv", "testdata/bankexport.csv"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	if !strings.Contains(w.String(), "Groceries") {
+		t.Errorf("Expected the categorised report to be printed, got %s", w.String())
+	}
+	after, err := os.ReadFile("testdata/bankexport.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("Expected the input report to be left unchanged")
+	}
+}
+
+func TestRunRulesKeepsTheOrderOfTheReport(t *testing.T) {
+	dir := t.TempDir()
+	reportPath, output := filepath.Join(dir, "report.csv"), filepath.Join(dir, "categorised.csv")
+	content := "Time,Amount,Description\n2025-02-01,-800.00,Landlord\n2025-02-02,0.00,Voided\n2025-02-03,2000.00,Salary\n2025-02-04,-25.50,REWE SAGT DANKE 1234"
+	if err := os.WriteFile(reportPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"rules", "-rules", "testdata/rules.csv", "-o", output, reportPath}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	expected := "Time,Amount,Description,Category\n2025-02-01,-800.00,Landlord,Rent\n2025-02-02,0.00,Voided,\n2025-02-03,2000.00,Salary,\n2025-02-04,-25.50,REWE SAGT DANKE 1234,Groceries"
+	actual, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Errorf("Expected the rows to keep their order\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestRunCategorize(t *testing.T) {
+	output := t.TempDir() + "/categorised.csv"
+	w := new(bytes.Buffer)
+	code := budget.Run(w, []string{"categorize", "-history", "testdata/history.csv", "-o", output, "testdata/bankexport.csv"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	if !strings.Contains(w.String(), "Bakery -> no suggestion") {
+		t.Errorf("Expected Bakery to have no suggestion, got %s", w.String())
+	}
+	categorised, err := report.ReadDefaultBudgetReportFromFile("January", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := currency.NewEuro(-33)
+	if actual := categorised.CalculateTotalExpensePerDescription()["Groceries"]; actual.Cmp(expected) != 0 {
+		t.Errorf("Expected %s of groceries, got %s", expected.String(), actual.String())
+	}
+}
+
+func TestRunNetWorthReportsFailedAssertions(t *testing.T) {
+	w := new(bytes.Buffer)
+	code := budget.Run(w, []string{"networth", "-accounts", "testdata/accounts.csv", "-assertions", "testdata/assertions.csv", "testdata/checking.csv", "testdata/savings.csv"})
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(w.String(), "2025-01-31,€2300.00,€5502.50,€7802.50") || !strings.Contains(w.String(), "1 of 2 balance assertion(s) do not hold") {
+		t.Errorf("Expected the net worth and failed assertion to be listed, got %s", w.String())
+	}
+}
+
+func TestScanPeriodDates(t *testing.T) {
+	from, to, err := budget.ScanPeriodDates(new(bytes.Buffer), bufio.NewScanner(strings.NewReader("2025-01-01 31.01.2025")))
+	if err != nil {
+		t.Fatalf("Got error reading period dates: %v", err)
+	}
+	if from.Format(time.DateOnly) != "2025-01-01" || to.Format(time.DateOnly) != "2025-01-31" {
+		t.Errorf("Expected the period 2025-01-01 to 2025-01-31, got %s to %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	for _, input := range []string{"", "2025-01-01", "2025-02-01 2025-01-01", "2025-01-01 tomorrow"} {
+		if _, _, err := budget.ScanPeriodDates(new(bytes.Buffer), bufio.NewScanner(strings.NewReader(input))); err == nil {
+			t.Errorf("Expected an error for input %q", input)
+		}
+	}
+}
+
+func TestRunForecast(t *testing.T) {
+	w := new(bytes.Buffer)
+	code := budget.Run(w, []string{"forecast", "-months", "2", "-recurring", "testdata/forecastrecurring.csv", "-balance", "500", "-from", "2025-04-01", "testdata/forecasthistory.csv"})
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "2025-05,€2500.00,€-2733.33,€-233.33,€33.34") || !strings.Contains(w.String(), "Forecast vs Actual") {
+		t.Errorf("Expected the forecast and its comparison with April, got %s", w.String())
+	}
+}
+
+func TestRunForecastWithoutDatesStartsThisMonth(t *testing.T) {
+	path := t.TempDir() + "/undated.csv"
+	if err := os.WriteFile(path, []byte("Time,Amount,Description\nsoon,-10,Coffee\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"forecast", "-months", "1", path}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	if month := time.Now().Format("2006-01") + ","; !strings.Contains(w.String(), month) {
+		t.Errorf("Expected the forecast to start this month, got %s", w.String())
+	}
+}
+
+func TestRunSubscriptions(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"subscriptions", "testdata/subscriptions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Spotify: €-4.99 monthly") {
+		t.Errorf("Expected Spotify to be detected, got %s", w.String())
+	}
+}
+
+func TestRunAnomalies(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"anomalies", "-history", "testdata/anomalyhistory.csv", "-threshold", "1000", "testdata/anomalyreport.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "duplicate charge: 2 charges of €-9.99 for Netflix") || strings.Contains(w.String(), "large transaction") {
+		t.Errorf("Expected the duplicate but no large transaction, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"anomalies", "-json", "testdata/anomalyreport.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), `"kind": "large transaction"`) {
+		t.Errorf("Expected the large transaction in the JSON output, got %s", w.String())
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"compare", "testdata/anomalyhistory.csv", "testdata/anomalyreport.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "New Categories: Gadgets, Streaming") {
+		t.Errorf("Expected the new categories, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"compare", "-csv", "-previous", "2025-02", "-current", "2025-03", "testdata/anomalyhistory.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Category,Fun,0.00,-60.00,-60.00,\n") {
+		t.Errorf("Expected the comparison of February and March as CSV, got %s", w.String())
+	}
+	if code := budget.Run(new(bytes.Buffer), []string{"compare", "-previous", "2025-02", "testdata/anomalyhistory.csv"}); code != 2 {
+		t.Errorf("Expected exit code 2 without a current period, got %d", code)
+	}
+}
+
+func TestRunGoals(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"goals", "-goals", "testdata/goals.csv", "testdata/goaltransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Savings Goals as of 2025-03-31\nHoliday: €600.00 of €1200.00 saved (50.00%)") {
+		t.Errorf("Expected the progress of the goals, got %s", w.String())
+	}
+}
+
+func TestRunEnvelopes(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"envelopes", "-envelopes", "testdata/envelopes.csv", "testdata/envelopetransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Income: €2000.00, Assigned: €1400.00, To Be Assigned: €1230.00") {
+		t.Errorf("Expected the amount to be assigned in February, got %s", w.String())
+	}
+}
+
+func TestRunLoans(t *testing.T) {
+	w := new(bytes.Buffer)
+	output := filepath.Join(t.TempDir(), "split.csv")
+	if code := budget.Run(w, []string{"loans", "-loans", "testdata/loans.csv", "-schedule", "-o", output, "testdata/loantransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "1,2025-02-15,€860.66,€50.00,€810.66,€9189.34\n") {
+		t.Errorf("Expected the amortisation schedule, got %s", w.String())
+	}
+	split, err := report.ReadDefaultBudgetReportFromFile("split", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split.Transactions()) != 5 {
+		t.Errorf("Expected the payments to be saved split, got %v", split.Transactions())
+	}
+}
+
+func TestRunLoansKeepsWhoPaid(t *testing.T) {
+	dir := t.TempDir()
+	path, output := filepath.Join(dir, "shared.csv"), filepath.Join(dir, "split.csv")
+	if err := os.WriteFile(path, []byte("Time,Amount,Description,Paid By\n2025-02-15,-860.66,Car loan payment,Joe\n2025-02-16,0,Voided,Charles\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"loans", "-loans", "testdata/loans.csv", "-o", output, path}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Time,Amount,Description,Name,Category\n2025-02-15,-50.00,Car loan payment,Joe,Loan:Car:Interest\n2025-02-15,-810.66,Car loan payment,Joe,Loan:Car:Principal\n2025-02-16,0.00,Voided,Charles,"
+	if actual := strings.TrimSpace(string(content)); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestRunInvestments(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"investments", "-trades", "testdata/trades.csv", "-prices", "testdata/prices.csv", "-as-of", "2025-04-30"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "VWCE,15,€130.00,€1950.00,€1576.50,€373.50,€73.50\n") {
+		t.Errorf("Expected the holdings at the April price, got %s", w.String())
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"metrics", "-accounts", "testdata/accounts.csv", "testdata/checking.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Metrics\nSavings Rate: 66.67%\n") || !strings.Contains(w.String(), "Months of Runway:") {
+		t.Errorf("Expected the metrics with the runway, got %s", w.String())
+	}
+}
+
+func TestRunTax(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "export.csv")
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"tax", "-config", "testdata/taxcodes.csv", "-o", output, "testdata/taxtransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Tax Summary for 2025\n") || !strings.Contains(w.String(), "WK Work equipment: €-1225.00 (2 transaction(s))\n") {
+		t.Errorf("Expected the 2025 tax summary, got %s", w.String())
+	}
+	export, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(export), "\n"); lines != 6 {
+		t.Errorf("Expected a header and 5 transactions in the export, got %s", export)
+	}
+}
+
+func TestRunTaxRequiresYearWithoutDates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.csv")
+	if err := os.WriteFile(path, []byte("Time,Amount,Description\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"tax", "-config", "testdata/taxcodes.csv", path}); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(w.String(), "-year is required") || strings.Contains(w.String(), "Tax Summary") {
+		t.Errorf("Expected -year to be required, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"tax", "-config", "testdata/taxcodes.csv", "-year", "2025", path}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+}
+
+func TestRunVAT(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"vat", "testdata/vattransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasPrefix(w.String(), "VAT Report for 2025 Q2\n") || !strings.HasSuffix(w.String(), "VAT Due: €404.42\n") {
+		t.Errorf("Expected the VAT report of the latest quarter, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"vat", "-quarter", "1", "testdata/vattransactions.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasSuffix(w.String(), "VAT Due: €190.00\n") {
+		t.Errorf("Expected the VAT report of the first quarter, got %s", w.String())
+	}
+}
+
+func TestRunVATRequiresQuarterWithoutDates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.csv")
+	if err := os.WriteFile(path, []byte("Time,Amount,Description\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"vat", "-year", "2025", path}); code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if !strings.Contains(w.String(), "-year and -quarter are required") || strings.Contains(w.String(), "VAT Report") {
+		t.Errorf("Expected -year and -quarter to be required, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"vat", "-year", "2025", "-quarter", "2", path}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+}
+
+func TestRunAttach(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.csv")
+	content, err := os.ReadFile("testdata/receipts.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := filepath.Join(dir, "monitor.pdf")
+	if err := os.WriteFile(reportPath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(receipt, []byte("monitor"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"receipts", "-threshold", "50", reportPath}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasPrefix(w.String(), "2 expense(s) of at least €50.00 lack a receipt\n") {
+		t.Errorf("Expected the monitor and train ticket to lack a receipt, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"attach", "-report", reportPath, "tx-1", receipt}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"receipts", reportPath}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasPrefix(w.String(), "1 expense(s) of at least €50.00 lack a receipt\n2025-03-09,tx-3,") {
+		t.Errorf("Expected only the train ticket to lack a receipt, got %s", w.String())
+	}
+	if code := budget.Run(w, []string{"attach", "-report", reportPath, "tx-9", receipt}); code != 1 {
+		t.Errorf("Expected exit code 1 for an unknown transaction, got %d", code)
+	}
+}
+
+func TestRunAttachKeepsTheOrderOfTheReport(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.csv")
+	content := "Time,Amount,Description,ID\n2025-03-15,2000.00,Salary,tx-4\n2025-03-02,-120.00,Monitor,tx-1\n2025-03-03,0.00,Voided,tx-5\n2025-03-09,-80.00,Train ticket,tx-3"
+	receipt := filepath.Join(dir, "monitor.pdf")
+	if err := os.WriteFile(reportPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(receipt, []byte("monitor"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"attach", "-report", reportPath, "tx-1", receipt}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	reference, err := attachment.Reference(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Time,Amount,Description,ID,Attachments\n2025-03-15,2000.00,Salary,tx-4,\n2025-03-02,-120.00,Monitor,tx-1," + reference + "\n2025-03-03,0.00,Voided,tx-5,\n2025-03-09,-80.00,Train ticket,tx-3,"
+	actual, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Errorf("Expected the rows to keep their order\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestRunReimbursements(t *testing.T) {
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"reimbursements", "testdata/refunds.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasSuffix(w.String(), "2 expense(s) with €140.00 outstanding\n") {
+		t.Errorf("Expected €140.00 outstanding, got %s", w.String())
+	}
+}
+
+func TestRunReconcile(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.csv")
+	content, err := os.ReadFile("testdata/reconcile.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(reportPath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reconcileArgs := []string{"reconcile", "-accounts", "testdata/accounts.csv", "-account", "Checking", "-date", "2025-03-31", "-balance", "3054.50"}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, append(reconcileArgs, "-lock", reportPath)); code != 1 {
+		t.Errorf("Expected exit code 1 locking an unbalanced reconciliation, got %d", code)
+	}
+	if !strings.Contains(w.String(), "Difference: €45.50\n2 uncleared transaction(s)\n") {
+		t.Errorf("Expected the difference and the uncleared transactions, got %s", w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, append(reconcileArgs, "-clear", "tx-3", "-lock", reportPath)); code != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	if !strings.Contains(w.String(), "Difference: €0.00\n1 uncleared transaction(s)\n") || !strings.HasSuffix(w.String(), "Locked Checking up to 2025-03-31\n") {
+		t.Errorf("Expected the reconciled period to be locked, got %s", w.String())
+	}
+	receipt := filepath.Join(dir, "rent.pdf")
+	if err := os.WriteFile(receipt, []byte("rent"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"attach", "-report", reportPath, "tx-2", receipt}); code != 1 || !strings.Contains(w.String(), "reconciled period") {
+		t.Errorf("Expected attaching to a reconciled transaction to fail, got %d: %s", code, w.String())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"attach", "-report", reportPath, "tx-5", receipt}); code != 0 {
+		t.Errorf("Expected attaching to a transaction after the reconciled period to succeed, got %d: %s", code, w.String())
+	}
+}
+
+func TestRunReconcileClearKeepsTheOrderOfTheReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.csv")
+	content := "Time,Amount,Description,ID,Account\n2025-03-05,-900.00,Rent,tx-2,Checking\n2025-03-01,3000.00,Salary,tx-1,Checking\n2025-03-03,0.00,Voided,tx-3,Checking"
+	if err := os.WriteFile(reportPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"reconcile", "-accounts", "testdata/accounts.csv", "-account", "Checking", "-date", "2025-03-31", "-balance", "3100", "-clear", "tx-1,tx-2", reportPath}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	expected := "Time,Amount,Description,ID,Account,Cleared\n2025-03-05,-900.00,Rent,tx-2,Checking,yes\n2025-03-01,3000.00,Salary,tx-1,Checking,yes\n2025-03-03,0.00,Voided,tx-3,Checking,"
+	actual, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Errorf("Expected the rows to keep their order\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "valid.csv")
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"validate", "-o", output, "testdata/invalidreport.csv"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if !strings.HasSuffix(w.String(), "testdata/invalidreport.csv: 7 problem(s), 4 of 8 row(s) skipped\n") {
+		t.Errorf("Expected the problems to be reported, got %s", w.String())
+	}
+	r, err := report.ReadDefaultBudgetReportFromFile("valid", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Transactions()) != 4 {
+		t.Errorf("Expected the 4 readable rows to be saved, got %v", r.Transactions())
+	}
+	w.Reset()
+	if code := budget.Run(w, []string{"validate", "-strict", "testdata/invalidreport.csv"}); code != 1 {
+		t.Errorf("Expected exit code 1 in strict mode, got %d", code)
+	}
+	if !strings.HasPrefix(w.String(), "testdata/invalidreport.csv:3:2: Amount \"12;50\": not a number\n") {
+		t.Errorf("Expected the problems to be listed in strict mode, got %s", w.String())
+	}
+}
+
+func TestRunValidateSavesAReadableFile(t *testing.T) {
+	dir := t.TempDir()
+	path, output := filepath.Join(dir, "report.csv"), filepath.Join(dir, "valid.csv")
+	contents := "Time,Amount,Description,ID,Category,Parent\n" +
+		"2025-03-04,-12,Bakery,tx-4,Food,\n" +
+		"2025-03-02,abc,Pharmacy,tx-2,,\n" +
+		",-5,,,Health,tx-2\n" +
+		"2025-03-01,0,Voided,tx-1,Food,\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	if code := budget.Run(w, []string{"validate", "-o", output, path}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, w.String())
+	}
+	transactions, _, err := report.ReadTransactionsFromFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 || transactions[0].Description != "Bakery" || transactions[1].Description != "Voided" {
+		t.Errorf("Expected the bakery and the voided row in the order of the file, got %v", transactions)
+	}
+}
@@ -0,0 +1,111 @@
+package classify
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/internal/text"
+)
+
+// Classifier is a naive Bayes classifier which learns from previously categorised transactions, and suggests
+// categories for new transactions from the words in their descriptions
+type Classifier struct {
+	examples       int
+	categoryCounts map[string]int
+	tokenCounts    map[string]map[string]int
+	tokenTotals    map[string]int
+	vocabulary     map[string]bool
+}
+
+// Suggestion is a suggested category, with the classifier's confidence between 0 and 1
+type Suggestion struct {
+	Category   string
+	Confidence float64
+}
+
+// String formats the suggestion with its confidence as a percentage
+func (s Suggestion) String() string {
+	return fmt.Sprintf("%s (%.0f%%)", s.Category, 100*s.Confidence)
+}
+
+// NewClassifier creates a classifier which has not learnt any categories yet
+func NewClassifier() *Classifier {
+	return &Classifier{categoryCounts: make(map[string]int), tokenCounts: make(map[string]map[string]int), tokenTotals: make(map[string]int), vocabulary: make(map[string]bool)}
+}
+
+// ReadTrainingFile trains a new classifier from a CSV file of previously categorised transactions, such as a saved
+// report. The file's header must name a Description and a Category column; other columns are ignored
+func ReadTrainingFile(path string) (*Classifier, error) {
+	classifier := NewClassifier()
+	err := csvfile.Read(path, []string{"Description", "Category"}, func(row csvfile.Row) error {
+		classifier.Train(row.Get("Description"), row.Get("Category"))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading training file: %w", err)
+	}
+	return classifier, nil
+}
+
+// Train teaches the classifier that a transaction with the description belongs to the category
+// Examples without a category are ignored
+func (c *Classifier) Train(description string, category string) {
+	if category == "" {
+		return
+	}
+	c.examples++
+	c.categoryCounts[category]++
+	if c.tokenCounts[category] == nil {
+		c.tokenCounts[category] = make(map[string]int)
+	}
+	for _, token := range tokenize(description) {
+		c.tokenCounts[category][token]++
+		c.tokenTotals[category]++
+		c.vocabulary[token] = true
+	}
+}
+
+// Suggest returns the most likely category for a transaction with the description. It returns false if the classifier
+// has not been trained, or none of the description's words have been seen before
+func (c *Classifier) Suggest(description string) (Suggestion, bool) {
+	tokens := slices.DeleteFunc(tokenize(description), func(token string) bool { return !c.vocabulary[token] })
+	if c.examples == 0 || len(tokens) == 0 {
+		return Suggestion{}, false
+	}
+	categories := slices.Sorted(maps.Keys(c.categoryCounts))
+	logProbabilities := make([]float64, len(categories))
+	for idx, category := range categories {
+		logProbability := math.Log(float64(c.categoryCounts[category]) / float64(c.examples))
+		for _, token := range tokens {
+			// Laplace smoothing, so that unseen words do not rule out a category
+			logProbability += math.Log(float64(c.tokenCounts[category][token]+1) / float64(c.tokenTotals[category]+len(c.vocabulary)))
+		}
+		logProbabilities[idx] = logProbability
+	}
+	best := 0
+	for idx := range logProbabilities {
+		if logProbabilities[idx] > logProbabilities[best] {
+			best = idx
+		}
+	}
+	// normalise the probabilities relative to the best category to avoid underflow
+	total := 0.0
+	for _, logProbability := range logProbabilities {
+		total += math.Exp(logProbability - logProbabilities[best])
+	}
+	return Suggestion{Category: categories[best], Confidence: 1 / total}, true
+}
+
+// tokenize returns the words of a description which are longer than a single letter
+func tokenize(description string) []string {
+	var tokens []string
+	for _, word := range text.Words(description) {
+		if len(word) > 1 {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
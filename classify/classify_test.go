@@ -0,0 +1,44 @@
+package classify_test
+
+import (
+	"testing"
+
+	"github.com/kevslinger/budget/classify"
+)
+
+func TestSuggest(t *testing.T) {
+	classifier, err := classify.ReadTrainingFile("../testdata/history.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"REWE SAGT DANKE 1234": "Groceries", "Hausverwaltung Miete Januar": "Rent", "AMAZON MKTPLACE": "Shopping"}
+	for description, category := range expected {
+		suggestion, ok := classifier.Suggest(description)
+		if !ok {
+			t.Errorf("Expected a suggestion for %s", description)
+			continue
+		}
+		if suggestion.Category != category {
+			t.Errorf("Expected %s to be categorised as %s, got %s", description, category, suggestion.String())
+		}
+		// better than picking one of the four categories at random
+		if suggestion.Confidence <= 0.25 || suggestion.Confidence > 1 {
+			t.Errorf("Expected a confident suggestion for %s, got %s", description, suggestion.String())
+		}
+	}
+}
+
+func TestSuggestWithoutKnownWords(t *testing.T) {
+	classifier := classify.NewClassifier()
+	if _, ok := classifier.Suggest("Bakery"); ok {
+		t.Errorf("Expected no suggestion from an untrained classifier")
+	}
+	classifier.Train("Bakery Schmidt", "Groceries")
+	if _, ok := classifier.Suggest("1234"); ok {
+		t.Errorf("Expected no suggestion for a description without words")
+	}
+	suggestion, ok := classifier.Suggest("Bakery Meyer")
+	if !ok || suggestion.Category != "Groceries" || suggestion.Confidence != 1 {
+		t.Errorf("Expected Groceries with full confidence, got %v", suggestion)
+	}
+}
@@ -7,5 +7,5 @@ import (
 )
 
 func main() {
-	os.Exit(budget.Main())
+	os.Exit(budget.Run(os.Stdout, os.Args[1:]))
 }
@@ -0,0 +1,1056 @@
+package budget
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/attachment"
+	"github.com/kevslinger/budget/classify"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/envelope"
+	"github.com/kevslinger/budget/forecast"
+	"github.com/kevslinger/budget/goal"
+	"github.com/kevslinger/budget/investment"
+	"github.com/kevslinger/budget/loan"
+	"github.com/kevslinger/budget/reconcile"
+	"github.com/kevslinger/budget/recurring"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/rules"
+	"github.com/kevslinger/budget/subscription"
+	"github.com/kevslinger/budget/tax"
+	"github.com/kevslinger/budget/transaction"
+	"github.com/kevslinger/budget/vat"
+)
+
+// Run starts the budget tracker with the given command-line arguments. Without arguments, the interactive report
+// builder is started, and otherwise the first argument names the command to run
+func Run(w io.Writer, args []string) int {
+	if len(args) == 0 {
+		return Main()
+	}
+	switch args[0] {
+	case "rules":
+		return RunRules(w, args[1:])
+	case "categorize":
+		return RunCategorize(w, args[1:])
+	case "networth":
+		return RunNetWorth(w, args[1:])
+	case "forecast":
+		return RunForecast(w, args[1:])
+	case "subscriptions":
+		return RunSubscriptions(w, args[1:])
+	case "anomalies":
+		return RunAnomalies(w, args[1:])
+	case "compare":
+		return RunCompare(w, args[1:])
+	case "goals":
+		return RunGoals(w, args[1:])
+	case "envelopes":
+		return RunEnvelopes(w, args[1:])
+	case "loans":
+		return RunLoans(w, args[1:])
+	case "investments":
+		return RunInvestments(w, args[1:])
+	case "metrics":
+		return RunMetrics(w, args[1:])
+	case "tax":
+		return RunTax(w, args[1:])
+	case "vat":
+		return RunVAT(w, args[1:])
+	case "attach":
+		return RunAttach(w, args[1:])
+	case "receipts":
+		return RunReceipts(w, args[1:])
+	case "reimbursements":
+		return RunReimbursements(w, args[1:])
+	case "reconcile":
+		return RunReconcile(w, args[1:])
+	case "validate":
+		return RunValidate(w, args[1:])
+	}
+	fmt.Fprintf(w, "Unknown command %q. Available commands: rules, categorize, networth, forecast, subscriptions, anomalies, compare, goals, envelopes, loans, investments, metrics, tax, vat, attach, receipts, reimbursements, reconcile, validate\n", args[0])
+	return 2
+}
+
+// RunRules categorises the transactions of a report file with a rules file, saving the result to a new report file or
+// printing it as CSV when no output file is given. In dry-run mode, it instead lists which rule matched each transaction
+func RunRules(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("rules", flag.ContinueOnError)
+	flags.SetOutput(w)
+	rulesPath := flags.String("rules", "rules.csv", "path to the CSV file of categorisation rules")
+	dryRun := flags.Bool("dry-run", false, "list which rule matched each transaction without saving anything")
+	output := flags.String("o", "", "path to save the categorised report to (default: print it as CSV)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(w, "Usage: budget rules [-rules rules.csv] [-dry-run] [-o output.csv] report.csv")
+		return 2
+	}
+	path := flags.Arg(0)
+	ruleSet, err := rules.ReadRulesFromFile(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the rules file! Error: ", err)
+		return 1
+	}
+	transactions, hasPayer, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	categorised, results := ruleSet.Apply(transactions)
+	if *dryRun {
+		for _, result := range results {
+			fmt.Fprintln(w, result.String())
+		}
+		fmt.Fprintf(w, "%d of %d transaction(s) matched no rule\n", len(rules.Uncategorised(results)), len(results))
+		return 0
+	}
+	if *output == "" {
+		if err := report.WriteTransactionsCSV(w, categorised, hasPayer); err != nil {
+			fmt.Fprintln(w, "There was an error printing the categorised report! Error: ", err)
+			return 1
+		}
+		return 0
+	}
+	PrintUncategorised(w, path, results)
+	if err := saveTransactions(*output, categorised, hasPayer); err != nil {
+		fmt.Fprintln(w, "There was an error saving the categorised report! Error: ", err)
+		return 1
+	}
+	return 0
+}
+
+// RunCategorize suggests a category for each uncategorised transaction of a report file, using a classifier trained on
+// previously categorised transactions. If an output file is given, suggestions with enough confidence are applied and saved to it
+func RunCategorize(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("categorize", flag.ContinueOnError)
+	flags.SetOutput(w)
+	historyPath := flags.String("history", "history.csv", "path to a CSV file of previously categorised transactions, such as a saved report with Description and Category columns")
+	minConfidence := flags.Float64("min-confidence", 0.6, "minimum confidence, between 0 and 1, for a suggestion to be applied")
+	output := flags.String("o", "", "path to save the categorised report to (default: only list the suggestions)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(w, "Usage: budget categorize [-history history.csv] [-min-confidence 0.6] [-o output.csv] report.csv")
+		return 2
+	}
+	path := flags.Arg(0)
+	classifier, err := classify.ReadTrainingFile(*historyPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the history file! Error: ", err)
+		return 1
+	}
+	transactions, hasPayer, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	applied := 0
+	for idx, tx := range transactions {
+		if tx.Category != "" {
+			continue
+		}
+		suggestion, ok := classifier.Suggest(tx.Description)
+		if !ok {
+			fmt.Fprintf(w, "%s,%s,%s -> no suggestion\n", tx.Time, tx.Amount.String(), tx.Description)
+			continue
+		}
+		fmt.Fprintf(w, "%s,%s,%s -> %s\n", tx.Time, tx.Amount.String(), tx.Description, suggestion.String())
+		if suggestion.Confidence >= *minConfidence {
+			transactions[idx].Category = suggestion.Category
+			applied++
+		}
+	}
+	fmt.Fprintf(w, "%d of %d transaction(s) had a suggestion with at least %.0f%% confidence\n", applied, len(transactions), 100**minConfidence)
+	if *output == "" {
+		return 0
+	}
+	if err := saveTransactions(*output, transactions, hasPayer); err != nil {
+		fmt.Fprintln(w, "There was an error saving the categorised report! Error: ", err)
+		return 1
+	}
+	return 0
+}
+
+// RunNetWorth prints the balance of each account and the net worth at the end of every month covered by the report
+// files, and checks the balance assertions if an assertions file is given
+func RunNetWorth(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("networth", flag.ContinueOnError)
+	flags.SetOutput(w)
+	accountsPath := flags.String("accounts", "accounts.csv", "path to a CSV file of accounts with their opening balances")
+	assertionsPath := flags.String("assertions", "", "path to a CSV file of balance assertions to check")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget networth [-accounts accounts.csv] [-assertions assertions.csv] report.csv...")
+		return 2
+	}
+	accounts, err := account.ReadAccountsFromFile(*accountsPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the accounts file! Error: ", err)
+		return 1
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	from, to := dateRange(transactions)
+	for _, a := range accounts {
+		if from.IsZero() || a.OpeningDate.Before(from) {
+			from = a.OpeningDate
+		}
+	}
+	fmt.Fprint(w, account.NewNetWorthReport(accounts, transactions, from, to).String())
+	if *assertionsPath == "" {
+		return 0
+	}
+	assertions, err := account.ReadAssertionsFromFile(*assertionsPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the assertions file! Error: ", err)
+		return 1
+	}
+	discrepancies, err := account.CheckAssertions(accounts, assertions, transactions)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error checking the balance assertions! Error: ", err)
+		return 1
+	}
+	if len(discrepancies) == 0 {
+		fmt.Fprintf(w, "All %d balance assertion(s) hold\n", len(assertions))
+		return 0
+	}
+	fmt.Fprintf(w, "%d of %d balance assertion(s) do not hold:\n", len(discrepancies), len(assertions))
+	for _, discrepancy := range discrepancies {
+		fmt.Fprintln(w, discrepancy.String())
+	}
+	return 1
+}
+
+// RunForecast projects the income, expense and balance of the coming months from the recurring transactions and the
+// average monthly amount of every other category in the report files. Months of the forecast which have closed are
+// compared with the transactions which actually happened in them
+func RunForecast(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	flags.SetOutput(w)
+	months := flags.Int("months", 6, "number of months to forecast")
+	recurringPath := flags.String("recurring", "", "path to a CSV file of recurring transactions")
+	accountsPath := flags.String("accounts", "", "path to a CSV file of accounts, whose balances are the opening balance")
+	balance := flags.Float64("balance", 0, "opening balance, if no accounts file is given")
+	fromDate := flags.String("from", "", "first month to forecast (default: the month after the latest transaction, or this month if none is dated)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || *months < 1 {
+		fmt.Fprintln(w, "Usage: budget forecast [-months 6] [-recurring recurring.csv] [-accounts accounts.csv | -balance 0] [-from 2025-01-01] report.csv...")
+		return 2
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	var definitions []recurring.Definition
+	if *recurringPath != "" {
+		if definitions, err = recurring.ReadDefinitionsFromFile(*recurringPath); err != nil {
+			fmt.Fprintln(w, "There was an error reading the recurring transactions file! Error: ", err)
+			return 1
+		}
+	}
+	_, latest := dateRange(transactions)
+	from := time.Date(latest.Year(), latest.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	if latest.IsZero() {
+		now := time.Now()
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if *fromDate != "" {
+		date, err := transaction.ParseTime(*fromDate)
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the first month to forecast! Error: ", err)
+			return 2
+		}
+		from = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	var history, actual []transaction.BasicTransaction
+	for _, tx := range transactions {
+		if date, err := tx.Date(); err == nil && !date.Before(from) {
+			actual = append(actual, tx)
+		} else {
+			history = append(history, tx)
+		}
+	}
+	openingBalance := currency.NewEuro(*balance)
+	if *accountsPath != "" {
+		accounts, err := account.ReadAccountsFromFile(*accountsPath)
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the accounts file! Error: ", err)
+			return 1
+		}
+		openingBalance = currency.NewEuro(0.0)
+		for _, a := range accounts {
+			if a.OpeningDate.Before(from) {
+				openingBalance = currency.AddEuros(openingBalance, a.BalanceAt(history, from.AddDate(0, 0, -1)))
+			}
+		}
+	}
+	projection := forecast.New(history, definitions, openingBalance, from, *months)
+	var str strings.Builder
+	str.WriteString(projection.String())
+	forecast.WriteComparisons(&str, projection.Compare(actual, time.Now()))
+	fmt.Fprint(w, str.String())
+	return 0
+}
+
+// RunSubscriptions lists the subscriptions detected among the transactions of the report files
+func RunSubscriptions(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("subscriptions", flag.ContinueOnError)
+	flags.SetOutput(w)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget subscriptions report.csv...")
+		return 2
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	fmt.Fprint(w, subscription.Report{Subscriptions: subscription.Detect(transactions)}.String())
+	return 0
+}
+
+// RunAnomalies prints a report file with the anomalies found in it, compared with the transactions of earlier report
+// files, either as text or as JSON
+func RunAnomalies(w io.Writer, args []string) int {
+	defaults := report.DefaultAnomalyOptions()
+	flags := flag.NewFlagSet("anomalies", flag.ContinueOnError)
+	flags.SetOutput(w)
+	historyPaths := flags.String("history", "", "comma-separated paths to earlier report files to compare with")
+	factor := flags.Float64("factor", defaults.SpikeFactor, "how many times its trailing average of up to 3 months a category's expense must exceed to be an anomaly (0 to disable)")
+	threshold := flags.Float64("threshold", float64(defaults.LargeExpense.Cents())/100, "size above which a single expense is an anomaly (0 to disable)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(w, "Usage: budget anomalies [-history earlier.csv,...] [-factor 1.5] [-threshold 500] [-json] report.csv")
+		return 2
+	}
+	r, err := report.ReadBudgetReportFromFile(flags.Arg(0), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	var history []transaction.BasicTransaction
+	if *historyPaths != "" {
+		if history, err = readBasicTransactions(strings.Split(*historyPaths, ",")); err != nil {
+			fmt.Fprintln(w, "There was an error reading the history files! Error: ", err)
+			return 1
+		}
+	}
+	r = report.DetectReportAnomalies(r, history, report.AnomalyOptions{SpikeFactor: *factor, LargeExpense: currency.NewEuro(*threshold)})
+	if *asJSON {
+		if err := r.WriteJSON(w); err != nil {
+			fmt.Fprintln(w, "There was an error writing the report! Error: ", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprint(w, r.String())
+	return 0
+}
+
+// RunCompare compares two report files, or two periods of the transactions in the report files, as text or as CSV
+func RunCompare(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	flags.SetOutput(w)
+	previousPeriod := flags.String("previous", "", "earlier period to compare, such as 2025-03 or 2024")
+	currentPeriod := flags.String("current", "", "later period to compare, such as 2025-04 or 2025")
+	asCSV := flags.Bool("csv", false, "print the comparison as CSV")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	byPeriod := *previousPeriod != "" || *currentPeriod != ""
+	if (byPeriod && (*previousPeriod == "" || *currentPeriod == "" || flags.NArg() == 0)) || (!byPeriod && flags.NArg() != 2) {
+		fmt.Fprintln(w, "Usage: budget compare [-csv] previous.csv current.csv")
+		fmt.Fprintln(w, "       budget compare [-csv] -previous 2025-03 -current 2025-04 report.csv...")
+		return 2
+	}
+	var comparison report.ComparisonReport
+	if byPeriod {
+		transactions, multiPayer, err := readPayerTransactions(flags.Args())
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+			return 1
+		}
+		if comparison, err = report.NewPeriodComparisonReport(transactions, *previousPeriod, *currentPeriod, multiPayer); err != nil {
+			fmt.Fprintln(w, "There was an error comparing the periods! Error: ", err)
+			return 1
+		}
+	} else {
+		var reports []report.Report
+		for _, path := range flags.Args() {
+			r, err := report.ReadBudgetReportFromFile(path, path)
+			if err != nil {
+				fmt.Fprintf(w, "There was an error reading the budget report file %s! Error: %v\n", path, err)
+				return 1
+			}
+			reports = append(reports, r)
+		}
+		var err error
+		if comparison, err = report.NewComparisonReport(reports[0], reports[1]); err != nil {
+			fmt.Fprintln(w, "There was an error comparing the reports! Error: ", err)
+			return 1
+		}
+	}
+	if *asCSV {
+		if err := comparison.WriteCSV(w); err != nil {
+			fmt.Fprintln(w, "There was an error writing the comparison! Error: ", err)
+			return 1
+		}
+		fmt.Fprintln(w)
+		return 0
+	}
+	fmt.Fprint(w, comparison.String())
+	return 0
+}
+
+// RunGoals reports the progress of the savings goals from the transactions of the report files
+func RunGoals(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("goals", flag.ContinueOnError)
+	flags.SetOutput(w)
+	goalsPath := flags.String("goals", "goals.csv", "path to a CSV file of savings goals")
+	asOfDate := flags.String("as-of", "", "date to report the progress on (default: the date of the latest transaction)")
+	accountsPath := flags.String("accounts", "", "path to a CSV file of accounts, whose opening balances count towards account goals")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget goals [-goals goals.csv] [-accounts accounts.csv] [-as-of 2025-04-30] report.csv...")
+		return 2
+	}
+	goals, err := goal.ReadGoalsFromFile(*goalsPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the goals file! Error: ", err)
+		return 1
+	}
+	var accounts []account.Account
+	if *accountsPath != "" {
+		if accounts, err = account.ReadAccountsFromFile(*accountsPath); err != nil {
+			fmt.Fprintln(w, "There was an error reading the accounts file! Error: ", err)
+			return 1
+		}
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	_, asOf := dateRange(transactions)
+	if *asOfDate != "" {
+		if asOf, err = transaction.ParseTime(*asOfDate); err != nil {
+			fmt.Fprintln(w, "There was an error reading the date to report the progress on! Error: ", err)
+			return 2
+		}
+	}
+	fmt.Fprint(w, goal.NewReport(goals, accounts, transactions, asOf).String())
+	return 0
+}
+
+// RunEnvelopes allocates the income in the report files to envelopes month by month, and draws them down with the
+// expenses
+func RunEnvelopes(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("envelopes", flag.ContinueOnError)
+	flags.SetOutput(w)
+	envelopesPath := flags.String("envelopes", "envelopes.csv", "path to a CSV file of envelopes with their monthly amounts")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget envelopes [-envelopes envelopes.csv] report.csv...")
+		return 2
+	}
+	envelopes, err := envelope.ReadEnvelopesFromFile(*envelopesPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the envelopes file! Error: ", err)
+		return 1
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	fmt.Fprint(w, envelope.NewBudget(envelopes, transactions).String())
+	return 0
+}
+
+// RunLoans splits the loan payments in the report files into interest and principal, and reports the remaining balance
+// and payoff of each loan, optionally with its amortisation schedule and what overpaying every month would do
+func RunLoans(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("loans", flag.ContinueOnError)
+	flags.SetOutput(w)
+	loansPath := flags.String("loans", "loans.csv", "path to a CSV file of loans")
+	extraPath := flags.String("extra", "", "path to a CSV file of one-off extra payments")
+	whatIf := flags.Float64("what-if", 0, "extra amount to pay every month in the overpayment scenario")
+	showSchedule := flags.Bool("schedule", false, "print the amortisation schedule of each loan")
+	output := flags.String("o", "", "path to save the transactions to, with the loan payments split")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget loans [-loans loans.csv] [-extra extra.csv] [-what-if 200] [-schedule] [-o split.csv] report.csv...")
+		return 2
+	}
+	loans, err := loan.ReadLoansFromFile(*loansPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the loans file! Error: ", err)
+		return 1
+	}
+	if *extraPath != "" {
+		if err := loan.ReadExtraPaymentsFromFile(*extraPath, loans); err != nil {
+			fmt.Fprintln(w, "There was an error reading the extra payments file! Error: ", err)
+			return 1
+		}
+	}
+	payerTransactions, hasPayer, err := readPayerTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	payerTransactions = report.SplitPayerLines(payerTransactions)
+	_, asOf := dateRange(basicTransactions(payerTransactions))
+	var str strings.Builder
+	for _, l := range loans {
+		payerTransactions = l.SplitPayers(payerTransactions)
+		str.WriteString(loan.NewReport(l, basicTransactions(payerTransactions), asOf, currency.NewEuro(*whatIf)).String())
+		if *showSchedule {
+			loan.WriteSchedule(&str, l.Schedule())
+		}
+	}
+	fmt.Fprint(w, str.String())
+	if *output != "" {
+		if err := saveTransactions(*output, payerTransactions, hasPayer); err != nil {
+			fmt.Fprintln(w, "There was an error saving the split transactions! Error: ", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// RunInvestments reports the value, cost basis and gains of the holdings bought and sold in a trades file, valued with
+// a local price file
+func RunInvestments(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("investments", flag.ContinueOnError)
+	flags.SetOutput(w)
+	tradesPath := flags.String("trades", "trades.csv", "path to a CSV file of buys and sells")
+	pricesPath := flags.String("prices", "prices.csv", "path to a CSV file of the prices of the securities")
+	asOfDate := flags.String("as-of", "", "date to value the holdings on (default: today)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(w, "Usage: budget investments [-trades trades.csv] [-prices prices.csv] [-as-of 2025-04-30]")
+		return 2
+	}
+	asOf := time.Now()
+	if *asOfDate != "" {
+		var err error
+		if asOf, err = transaction.ParseTime(*asOfDate); err != nil {
+			fmt.Fprintln(w, "There was an error reading the date to value the holdings on! Error: ", err)
+			return 2
+		}
+	}
+	trades, err := investment.ReadTradesFromFile(*tradesPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the trades file! Error: ", err)
+		return 1
+	}
+	prices, err := investment.ReadPricesFromFile(*pricesPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the prices file! Error: ", err)
+		return 1
+	}
+	holdings, err := investment.Holdings(trades, prices, asOf)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error applying the trades! Error: ", err)
+		return 1
+	}
+	fmt.Fprint(w, investment.Report{AsOf: asOf, Holdings: holdings}.String())
+	return 0
+}
+
+// RunMetrics prints a report file with its metrics, either as text or as JSON. The current balance for the months of
+// runway is either given, or the total balance of the accounts at the report's last transaction
+func RunMetrics(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	flags.SetOutput(w)
+	classificationPath := flags.String("classification", "", "path to a CSV file classifying categories as essential or discretionary, and fixed")
+	accountsPath := flags.String("accounts", "", "path to a CSV file of accounts, whose balances are the current balance")
+	balance := flags.String("balance", "", "current balance, if no accounts file is given")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(w, "Usage: budget metrics [-classification classification.csv] [-accounts accounts.csv | -balance 1000] [-json] report.csv")
+		return 2
+	}
+	r, err := report.ReadBudgetReportFromFile(flags.Arg(0), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	var opts report.MetricsOptions
+	if *classificationPath != "" {
+		if opts.Classification, err = report.ReadClassificationFromFile(*classificationPath); err != nil {
+			fmt.Fprintln(w, "There was an error reading the classification file! Error: ", err)
+			return 1
+		}
+	}
+	if *balance != "" {
+		amount, err := strconv.ParseFloat(*balance, 64)
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the current balance! Error: ", err)
+			return 2
+		}
+		current := currency.NewEuro(amount)
+		opts.Balance = &current
+	} else if *accountsPath != "" {
+		accounts, err := account.ReadAccountsFromFile(*accountsPath)
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the accounts file! Error: ", err)
+			return 1
+		}
+		transactions, err := readBasicTransactions(flags.Args())
+		if err != nil {
+			fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+			return 1
+		}
+		_, to := dateRange(transactions)
+		current := currency.NewEuro(0.0)
+		for _, a := range accounts {
+			current = currency.AddEuros(current, a.BalanceAt(transactions, to))
+		}
+		opts.Balance = &current
+	}
+	r = report.ComputeReportMetrics(r, opts)
+	if *asJSON {
+		if err := r.WriteJSON(w); err != nil {
+			fmt.Fprintln(w, "There was an error writing the report! Error: ", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprint(w, r.String())
+	return 0
+}
+
+// RunTax summarises the tax-relevant transactions of a year in the report files by tax code, and optionally exports
+// them as CSV for a tax advisor
+func RunTax(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("tax", flag.ContinueOnError)
+	flags.SetOutput(w)
+	configPath := flags.String("config", "tax.csv", "path to a CSV file of the tax codes of categories and tags")
+	year := flags.Int("year", 0, "year to summarise (default: the year of the latest transaction)")
+	output := flags.String("o", "", "path to export the tax-relevant transactions to")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget tax [-config tax.csv] [-year 2025] [-o export.csv] report.csv...")
+		return 2
+	}
+	taxRules, err := tax.ReadRulesFromFile(*configPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the tax file! Error: ", err)
+		return 1
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	if *year == 0 {
+		_, to := dateRange(transactions)
+		if to.IsZero() {
+			fmt.Fprintln(w, "None of the transactions has a date, so -year is required")
+			return 2
+		}
+		*year = to.Year()
+	}
+	summary := tax.NewSummary(taxRules, transactions, *year)
+	fmt.Fprint(w, summary.String())
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(w, "There was an error creating the tax export! Error: ", err)
+			return 1
+		}
+		if err := summary.WriteCSV(file); err != nil {
+			file.Close()
+			fmt.Fprintln(w, "There was an error writing the tax export! Error: ", err)
+			return 1
+		}
+		if err := file.Close(); err != nil {
+			fmt.Fprintln(w, "There was an error writing the tax export! Error: ", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// RunVAT reports the VAT collected and paid in a quarter of the transactions in the report files, and the amount due
+func RunVAT(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("vat", flag.ContinueOnError)
+	flags.SetOutput(w)
+	year := flags.Int("year", 0, "year of the quarter to report (default: the year of the latest transaction)")
+	quarter := flags.Int("quarter", 0, "quarter to report, from 1 to 4 (default: the quarter of the latest transaction)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || *quarter < 0 || *quarter > 4 {
+		fmt.Fprintln(w, "Usage: budget vat [-year 2025] [-quarter 2] report.csv...")
+		return 2
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	_, to := dateRange(transactions)
+	if to.IsZero() && (*year == 0 || *quarter == 0) {
+		fmt.Fprintln(w, "None of the transactions has a date, so -year and -quarter are required")
+		return 2
+	}
+	if *year == 0 {
+		*year = to.Year()
+	}
+	if *quarter == 0 {
+		*quarter = vat.Quarter(to)
+	}
+	fmt.Fprint(w, vat.NewReport(transactions, *year, *quarter).String())
+	return 0
+}
+
+// RunAttach copies a file, such as a receipt, into the attachment store next to a report file, and references it from
+// the transaction with an ID
+func RunAttach(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("attach", flag.ContinueOnError)
+	flags.SetOutput(w)
+	reportPath := flags.String("report", "report.csv", "path to the report file with the transaction")
+	storeDir := flags.String("store", "", "path to the attachment store (default: the attachments directory next to the report file)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(w, "Usage: budget attach [-report report.csv] [-store attachments] <transaction ID> file.pdf")
+		return 2
+	}
+	id, path := flags.Arg(0), flags.Arg(1)
+	store := attachment.StoreFor(*reportPath)
+	if *storeDir != "" {
+		store = attachment.Store{Dir: *storeDir}
+	}
+	transactions, hasPayer, err := report.ReadTransactionsFromFile(*reportPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	reference, err := attachment.Reference(path)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the attachment! Error: ", err)
+		return 1
+	}
+	if !attachment.Attach(transactions, id, reference) {
+		fmt.Fprintf(w, "There is no transaction with ID %q in %s\n", id, *reportPath)
+		return 1
+	}
+	if _, err := store.Add(path); err != nil {
+		fmt.Fprintln(w, "There was an error storing the attachment! Error: ", err)
+		return 1
+	}
+	if err := saveTransactions(*reportPath, transactions, hasPayer); err != nil {
+		fmt.Fprintln(w, "There was an error saving the budget report! Error: ", err)
+		return 1
+	}
+	fmt.Fprintf(w, "Attached %s to transaction %s as %s\n", path, id, reference)
+	return 0
+}
+
+// RunReceipts lists the expenses in the report files above a threshold which lack a receipt in the attachment store
+func RunReceipts(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("receipts", flag.ContinueOnError)
+	flags.SetOutput(w)
+	threshold := flags.Float64("threshold", 50, "smallest expense which needs a receipt")
+	storeDir := flags.String("store", "", "path to the attachment store (default: the attachments directory next to the first report file)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget receipts [-threshold 50] [-store attachments] report.csv...")
+		return 2
+	}
+	store := attachment.StoreFor(flags.Arg(0))
+	if *storeDir != "" {
+		store = attachment.Store{Dir: *storeDir}
+	}
+	transactions, err := readTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	missing := store.MissingReceipts(transactions, currency.NewEuro(*threshold))
+	fmt.Fprintf(w, "%d expense(s) of at least %s lack a receipt\n", len(missing), currency.NewEuro(*threshold).String())
+	for _, tx := range missing {
+		fmt.Fprintf(w, "%s,%s,%s,%s\n", tx.Time, tx.ID, tx.Amount.String(), tx.Description)
+	}
+	return 0
+}
+
+// RunReimbursements lists the reimbursable expenses in the report files which have not been fully paid back
+func RunReimbursements(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("reimbursements", flag.ContinueOnError)
+	flags.SetOutput(w)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(w, "Usage: budget reimbursements report.csv...")
+		return 2
+	}
+	transactions, err := readBasicTransactions(flags.Args())
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report files! Error: ", err)
+		return 1
+	}
+	outstanding := report.OutstandingReimbursements(transactions)
+	total := currency.NewEuro(0.0)
+	for _, r := range outstanding {
+		fmt.Fprintln(w, r.String())
+		total = currency.AddEuros(total, r.Outstanding())
+	}
+	fmt.Fprintf(w, "%d expense(s) with %s outstanding\n", len(outstanding), total.String())
+	return 0
+}
+
+// RunReconcile compares the cleared transactions of an account in a report file with the closing balance of a bank
+// statement, listing the uncleared transactions. It can mark transactions as cleared, saving the report file, and lock
+// the reconciled period once the balances match
+func RunReconcile(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(w)
+	accountsPath := flags.String("accounts", "accounts.csv", "path to a CSV file of accounts with their opening balances")
+	accountName := flags.String("account", "", "name of the account the statement is for")
+	statementDate := flags.String("date", "", "end date of the statement")
+	balance := flags.String("balance", "", "closing balance of the statement")
+	clearIDs := flags.String("clear", "", "comma-separated IDs of the transactions to mark as cleared")
+	clearAll := flags.Bool("clear-all", false, "mark every transaction of the account up to the statement date as cleared")
+	lock := flags.Bool("lock", false, "lock the reconciled period from further edits, if the balances match")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *accountName == "" || *statementDate == "" || *balance == "" {
+		fmt.Fprintln(w, "Usage: budget reconcile [-accounts accounts.csv] -account Checking -date 2025-03-31 -balance 1234.56 [-clear id,id | -clear-all] [-lock] report.csv")
+		return 2
+	}
+	path := flags.Arg(0)
+	date, err := transaction.ParseTime(*statementDate)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the statement date! Error: ", err)
+		return 2
+	}
+	amount, err := strconv.ParseFloat(*balance, 64)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the statement balance! Error: ", err)
+		return 2
+	}
+	accounts, err := account.ReadAccountsFromFile(*accountsPath)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the accounts file! Error: ", err)
+		return 1
+	}
+	idx := slices.IndexFunc(accounts, func(a account.Account) bool { return a.Name == *accountName })
+	if idx < 0 {
+		fmt.Fprintf(w, "There is no account named %q in %s\n", *accountName, *accountsPath)
+		return 1
+	}
+	transactions, hasPayer, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		fmt.Fprintln(w, "There was an error reading the budget report file! Error: ", err)
+		return 1
+	}
+	if *clearAll || *clearIDs != "" {
+		var ids []string
+		if !*clearAll {
+			ids = strings.Split(*clearIDs, ",")
+		}
+		if err := reconcile.Clear(transactions, *accountName, date, ids); err != nil {
+			fmt.Fprintln(w, "There was an error clearing the transactions! Error: ", err)
+			return 1
+		}
+		if err := saveTransactions(path, transactions, hasPayer); err != nil {
+			fmt.Fprintln(w, "There was an error saving the budget report! Error: ", err)
+			return 1
+		}
+	}
+	var basic []transaction.BasicTransaction
+	for _, tx := range transactions {
+		basic = append(basic, tx.BasicTransaction)
+	}
+	reconciliation := reconcile.New(accounts[idx], report.SplitLines(basic), date, currency.NewEuro(amount))
+	fmt.Fprint(w, reconciliation.String())
+	if *lock {
+		if err := reconcile.Lock(path, reconciliation); err != nil {
+			fmt.Fprintln(w, "There was an error locking the reconciled period! Error: ", err)
+			return 1
+		}
+		fmt.Fprintf(w, "Locked %s up to %s\n", *accountName, date.Format(time.DateOnly))
+	}
+	return 0
+}
+
+// RunValidate lists every problem in the report files with its file, line and column. In strict mode any problem is
+// an error, and in lenient mode the rows which cannot be read are skipped, and the other rows of a single report file
+// can be saved to a new file
+func RunValidate(w io.Writer, args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(w)
+	strict := flags.Bool("strict", false, "fail if there are any problems, rather than skipping the rows which cannot be read")
+	output := flags.String("o", "", "path to save the rows which can be read to, in lenient mode")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (*output != "" && (*strict || flags.NArg() != 1)) {
+		fmt.Fprintln(w, "Usage: budget validate [-strict | -o valid.csv] report.csv...")
+		return 2
+	}
+	mode := report.Lenient
+	if *strict {
+		mode = report.Strict
+	}
+	code := 0
+	for _, path := range flags.Args() {
+		validation, err := report.ValidateTransactionsFile(path, mode)
+		for _, problem := range validation.Problems {
+			fmt.Fprintln(w, problem.Error())
+		}
+		if err != nil {
+			fmt.Fprintln(w, "There was an error validating the budget report file! Error: ", err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d problem(s), %d of %d row(s) skipped\n", path, len(validation.Problems), validation.Skipped(), validation.Rows)
+		if *output != "" {
+			if err := saveTransactions(*output, validation.Transactions, validation.HasPayer); err != nil {
+				fmt.Fprintln(w, "There was an error saving the valid rows! Error: ", err)
+				return 1
+			}
+		}
+	}
+	return code
+}
+
+// saveTransactions saves the transactions to a report file in the order they were read, refusing to add, remove or
+// change transactions in periods of the file which have been reconciled
+func saveTransactions(path string, transactions []transaction.PayerTransaction, hasPayer bool) error {
+	if err := reconcile.CheckSave(path, transactions); err != nil {
+		return err
+	}
+	return report.SaveTransactions(path, transactions, hasPayer)
+}
+
+// saveReport saves a report to a file, refusing to add, remove or change transactions in periods of the file which have
+// been reconciled
+func saveReport(r report.Report, path string) error {
+	var transactions []transaction.PayerTransaction
+	switch r := r.(type) {
+	case report.BasicReport:
+		for _, tx := range r.Transactions() {
+			transactions = append(transactions, transaction.PayerTransaction{BasicTransaction: tx})
+		}
+	case report.MultiPayerReport:
+		transactions = r.Transactions()
+	}
+	if err := reconcile.CheckSave(path, transactions); err != nil {
+		return err
+	}
+	return r.Save(path)
+}
+
+// readBasicTransactions reads the transactions of several report files, ignoring who earned/paid them, with every split
+// transaction replaced by its child lines
+func readBasicTransactions(paths []string) ([]transaction.BasicTransaction, error) {
+	transactions, err := readTransactions(paths)
+	if err != nil {
+		return nil, err
+	}
+	return report.SplitLines(transactions), nil
+}
+
+// readTransactions reads the transactions of several report files, ignoring who earned/paid them, keeping split
+// transactions together with their child lines
+func readTransactions(paths []string) ([]transaction.BasicTransaction, error) {
+	payerTransactions, _, err := readPayerTransactions(paths)
+	if err != nil {
+		return nil, err
+	}
+	return basicTransactions(payerTransactions), nil
+}
+
+// readPayerTransactions reads the transactions of several report files, keeping who earned/paid them and split
+// transactions together with their child lines, and resolving refunds of transactions in the other files. It reports
+// whether any of the files has a payer column
+func readPayerTransactions(paths []string) ([]transaction.PayerTransaction, bool, error) {
+	var transactions []transaction.PayerTransaction
+	hasPayer := false
+	for _, path := range paths {
+		payerTransactions, filePayer, err := report.ReadTransactionsFromFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", path, err)
+		}
+		transactions = append(transactions, payerTransactions...)
+		hasPayer = hasPayer || filePayer
+	}
+	if err := report.ResolveRefunds(transactions); err != nil {
+		return nil, false, err
+	}
+	return transactions, hasPayer, nil
+}
+
+// basicTransactions returns the transactions without who earned/paid them
+func basicTransactions(payerTransactions []transaction.PayerTransaction) []transaction.BasicTransaction {
+	var transactions []transaction.BasicTransaction
+	for _, tx := range payerTransactions {
+		transactions = append(transactions, tx.BasicTransaction)
+	}
+	return transactions
+}
+
+// dateRange returns the earliest and latest dates of the transactions, ignoring those whose time is not a date
+func dateRange(transactions []transaction.BasicTransaction) (time.Time, time.Time) {
+	var from, to time.Time
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil {
+			continue
+		}
+		if from.IsZero() || date.Before(from) {
+			from = date
+		}
+		if date.After(to) {
+			to = date
+		}
+	}
+	return from, to
+}
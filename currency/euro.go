@@ -1,17 +1,31 @@
 package currency
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
 
 type Euro struct {
 	cents int64
 }
 
+// NewEuro converts an amount of euros to a Euro, rounding to the nearest cent so that amounts such as 19.99, which
+// floats cannot represent exactly, keep their last cent
 func NewEuro(euros float64) Euro {
-	return Euro{cents: int64(euros * 100)}
+	return Euro{cents: int64(math.Round(euros * 100))}
+}
+
+func NewEuroFromCents(cents int64) Euro {
+	return Euro{cents: cents}
 }
 
 func AddEuros(e, e2 Euro) Euro {
-	return NewEuro(float64(e.cents+e2.cents) / 100)
+	return Euro{cents: e.cents + e2.cents}
+}
+
+func SubtractEuros(e, e2 Euro) Euro {
+	return Euro{cents: e.cents - e2.cents}
 }
 
 func (e Euro) Cents() int64 {
@@ -31,3 +45,22 @@ func (e Euro) Cmp(e2 Euro) int {
 func (e Euro) String() string {
 	return fmt.Sprintf("€%.2f", float64(e.cents)/100)
 }
+
+// MarshalJSON encodes the amount as a number of euros with two decimal places, e.g. -12.50
+func (e Euro) MarshalJSON() ([]byte, error) {
+	sign, cents := "", e.cents
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return []byte(fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)), nil
+}
+
+// UnmarshalJSON decodes an amount encoded by MarshalJSON, rounding to the nearest cent
+func (e *Euro) UnmarshalJSON(data []byte) error {
+	euros, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("error decoding amount %s: %w", data, err)
+	}
+	e.cents = int64(math.Round(euros * 100))
+	return nil
+}
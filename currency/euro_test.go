@@ -30,3 +30,57 @@ func TestCmpEuro(t *testing.T) {
 		t.Errorf("Expected e2 %s and e1 %s to be the same", e2.String(), e1.String())
 	}
 }
+
+func TestAddEurosKeepsEveryCent(t *testing.T) {
+	actual := currency.AddEuros(currency.NewEuroFromCents(20), currency.NewEuroFromCents(9))
+	if actual.Cents() != 29 {
+		t.Errorf("Expected 29 cents, got %d", actual.Cents())
+	}
+}
+
+func TestSubtractEuros(t *testing.T) {
+	actual := currency.SubtractEuros(currency.NewEuro(10), currency.NewEuro(12.5))
+	expected := currency.NewEuro(-2.5)
+	if actual.Cmp(expected) != 0 {
+		t.Errorf("Expected %s but got %s", expected, actual)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	amounts := map[int64]string{0: "0.00", 5: "0.05", -5: "-0.05", 1250: "12.50", -123456: "-1234.56"}
+	for cents, expected := range amounts {
+		actual, err := currency.NewEuroFromCents(cents).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != expected {
+			t.Errorf("Expected %d cents to be encoded as %s, got %s", cents, expected, actual)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	amounts := map[string]int64{"0": 0, "0.05": 5, "-0.05": -5, "12.5": 1250, "-1234.56": -123456, "19.99": 1999}
+	for data, expected := range amounts {
+		var actual currency.Euro
+		if err := actual.UnmarshalJSON([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if actual.Cents() != expected {
+			t.Errorf("Expected %s to be decoded as %d cents, got %d", data, expected, actual.Cents())
+		}
+	}
+	var invalid currency.Euro
+	if err := invalid.UnmarshalJSON([]byte(`"twelve"`)); err == nil {
+		t.Errorf("Expected an error decoding a string")
+	}
+}
+
+func TestNewEuroRoundsToTheNearestCent(t *testing.T) {
+	amounts := map[float64]int64{19.99: 1999, -19.99: -1999, 0.29: 29, 0.125: 13, -0.125: -13, 4.994: 499}
+	for euros, expected := range amounts {
+		if actual := currency.NewEuro(euros).Cents(); actual != expected {
+			t.Errorf("Expected %v euros to be %d cents, got %d", euros, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package envelope
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Envelope is a category which is assigned an amount of income every month, and which expenses in the category or
+// its sub-categories draw down
+type Envelope struct {
+	Name    string
+	Monthly currency.Euro
+	// Rollover keeps an envelope's unspent amount for the next month. Otherwise it is returned to be assigned again.
+	// Overspending is carried into the next month either way
+	Rollover bool
+}
+
+// ReadEnvelopesFromFile reads envelopes from a CSV file with the columns Name,Monthly and optionally Rollover, which
+// defaults to yes
+func ReadEnvelopesFromFile(path string) ([]Envelope, error) {
+	var envelopes []Envelope
+	err := csvfile.Read(path, []string{"Name", "Monthly"}, func(row csvfile.Row) error {
+		envelope := Envelope{Name: row.Get("Name"), Rollover: true}
+		if envelope.Name == "" {
+			return fmt.Errorf("missing envelope name")
+		}
+		var err error
+		if envelope.Monthly, err = row.Amount("Monthly"); err != nil {
+			return err
+		}
+		if row.Get("Rollover") != "" {
+			if envelope.Rollover, err = row.Bool("Rollover"); err != nil {
+				return err
+			}
+		}
+		envelopes = append(envelopes, envelope)
+		return nil
+	})
+	return envelopes, err
+}
+
+// contains reports whether the envelope funds the transaction's category
+func (e Envelope) contains(tx transaction.BasicTransaction) bool {
+	category := tx.EffectiveCategory()
+	return category == e.Name || strings.HasPrefix(category, e.Name+report.CategorySeparator)
+}
+
+// EnvelopeMonth is the activity of one envelope in a month
+type EnvelopeMonth struct {
+	Name string
+	// Carried is the balance carried over from the previous month
+	Carried  currency.Euro
+	Assigned currency.Euro
+	Spent    currency.Euro
+	Balance  currency.Euro
+}
+
+// Month is the income, assignments and envelopes of a month
+type Month struct {
+	// Start is the first day of the month
+	Start    time.Time
+	Income   currency.Euro
+	Assigned currency.Euro
+	// Unbudgeted are the expenses which no envelope funds, which are paid from the amount to be assigned
+	Unbudgeted currency.Euro
+	// ToBeAssigned is the income, including income carried over from earlier months, which is not in an envelope
+	ToBeAssigned currency.Euro
+	Envelopes    []EnvelopeMonth
+}
+
+// Budget is the envelope budget of every month from the first to the last dated transaction
+type Budget struct {
+	Months []Month
+}
+
+// NewBudget allocates the income of each month to the envelopes, and draws them down with the month's expenses.
+// Transfers, investments and transactions whose time is not a date are ignored. An expense is drawn from the first envelope which
+// funds its category
+func NewBudget(envelopes []Envelope, transactions []transaction.BasicTransaction) Budget {
+	var first, last time.Time
+	for _, tx := range transactions {
+		if date, err := tx.Date(); err == nil {
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+			if date.After(last) {
+				last = date
+			}
+		}
+	}
+	var budget Budget
+	if first.IsZero() {
+		return budget
+	}
+	balances := make([]currency.Euro, len(envelopes))
+	toBeAssigned := currency.NewEuro(0.0)
+	for start := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !start.After(last); start = start.AddDate(0, 1, 0) {
+		month := Month{Start: start}
+		spent := make([]currency.Euro, len(envelopes))
+		for _, tx := range transactions {
+			date, err := tx.Date()
+			if err != nil || !tx.IsIncomeOrExpense() || date.Before(start) || !date.Before(start.AddDate(0, 1, 0)) {
+				continue
+			}
+			if tx.IsIncome() {
+				month.Income = currency.AddEuros(month.Income, tx.Amount)
+				continue
+			}
+			idx := -1
+			for envelopeIdx, envelope := range envelopes {
+				if envelope.contains(tx) {
+					idx = envelopeIdx
+					break
+				}
+			}
+			if idx < 0 {
+				month.Unbudgeted = currency.AddEuros(month.Unbudgeted, tx.Amount)
+			} else {
+				spent[idx] = currency.AddEuros(spent[idx], tx.Amount)
+			}
+		}
+		toBeAssigned = currency.AddEuros(toBeAssigned, currency.AddEuros(month.Income, month.Unbudgeted))
+		for idx, envelope := range envelopes {
+			carried := balances[idx]
+			if !envelope.Rollover && carried.Cmp(currency.NewEuro(0.0)) > 0 {
+				toBeAssigned = currency.AddEuros(toBeAssigned, carried)
+				carried = currency.NewEuro(0.0)
+			}
+			balances[idx] = currency.AddEuros(currency.AddEuros(carried, envelope.Monthly), spent[idx])
+			month.Assigned = currency.AddEuros(month.Assigned, envelope.Monthly)
+			month.Envelopes = append(month.Envelopes, EnvelopeMonth{Name: envelope.Name, Carried: carried, Assigned: envelope.Monthly, Spent: spent[idx], Balance: balances[idx]})
+		}
+		toBeAssigned = currency.SubtractEuros(toBeAssigned, month.Assigned)
+		month.ToBeAssigned = toBeAssigned
+		budget.Months = append(budget.Months, month)
+	}
+	return budget
+}
+
+// String returns, for every month, the income, the amount assigned and to be assigned, and a table of the envelopes
+func (b Budget) String() string {
+	var str strings.Builder
+	for _, month := range b.Months {
+		str.WriteString(fmt.Sprintf("Envelopes for %s\n", month.Start.Format("2006-01")))
+		str.WriteString(fmt.Sprintf("Income: %s, Assigned: %s, To Be Assigned: %s\n", month.Income.String(), month.Assigned.String(), month.ToBeAssigned.String()))
+		if month.Unbudgeted.Cents() != 0 {
+			str.WriteString(fmt.Sprintf("Unbudgeted Expenses: %s\n", month.Unbudgeted.String()))
+		}
+		str.WriteString("Envelope,Carried,Assigned,Spent,Balance\n")
+		for _, envelope := range month.Envelopes {
+			str.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", envelope.Name, envelope.Carried.String(), envelope.Assigned.String(), envelope.Spent.String(), envelope.Balance.String()))
+		}
+	}
+	return str.String()
+}
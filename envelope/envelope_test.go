@@ -0,0 +1,45 @@
+package envelope_test
+
+import (
+	"testing"
+
+	"github.com/kevslinger/budget/envelope"
+	"github.com/kevslinger/budget/report"
+)
+
+func TestBudget(t *testing.T) {
+	envelopes, err := envelope.ReadEnvelopesFromFile("../testdata/envelopes.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !envelopes[1].Rollover || envelopes[0].Rollover {
+		t.Errorf("Expected envelopes to roll over unless they say no, got %v", envelopes)
+	}
+	r, err := report.ReadDefaultBudgetReportFromFile("envelopes", "../testdata/envelopetransactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Envelopes for 2025-01\n" +
+		"Income: €2000.00, Assigned: €1400.00, To Be Assigned: €580.00\n" +
+		"Unbudgeted Expenses: €-20.00\n" +
+		"Envelope,Carried,Assigned,Spent,Balance\n" +
+		"Housing,€0.00,€900.00,€-850.00,€50.00\n" +
+		"Food,€0.00,€400.00,€-300.00,€100.00\n" +
+		"Fun,€0.00,€100.00,€-150.00,€-50.00\n" +
+		"Envelopes for 2025-02\n" +
+		// the €50.00 left in Housing is returned to be assigned, as it does not roll over
+		"Income: €2000.00, Assigned: €1400.00, To Be Assigned: €1230.00\n" +
+		"Envelope,Carried,Assigned,Spent,Balance\n" +
+		"Housing,€0.00,€900.00,€-900.00,€0.00\n" +
+		"Food,€100.00,€400.00,€-480.00,€20.00\n" +
+		"Fun,€-50.00,€100.00,€0.00,€50.00\n"
+	if actual := envelope.NewBudget(envelopes, r.Transactions()).String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestBudgetWithoutTransactions(t *testing.T) {
+	if budget := envelope.NewBudget([]envelope.Envelope{{Name: "Food"}}, nil); len(budget.Months) != 0 {
+		t.Errorf("Expected no months, got %v", budget.Months)
+	}
+}
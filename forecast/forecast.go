@@ -0,0 +1,193 @@
+package forecast
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/recurring"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Month is the projected income and expense of a month, and the balance at its end
+type Month struct {
+	// Start is the first day of the month
+	Start   time.Time
+	Income  currency.Euro
+	Expense currency.Euro
+	Balance currency.Euro
+}
+
+// Net returns the income minus the expense of the month
+func (m Month) Net() currency.Euro {
+	return currency.AddEuros(m.Income, m.Expense)
+}
+
+// Forecast projects the balance forward month by month from the recurring transactions, and the average monthly
+// amount of every other category
+type Forecast struct {
+	OpeningBalance currency.Euro
+	// Averages is the average monthly amount per category which is not covered by a recurring transaction
+	Averages map[string]currency.Euro
+	Months   []Month
+}
+
+// New forecasts months months from the month of start, beginning with the opening balance. The recurring transactions
+// are projected from their definitions, and every other category from its monthly average in the history. Transfers,
+// investments and generated transactions in the history are ignored
+func New(history []transaction.BasicTransaction, definitions []recurring.Definition, openingBalance currency.Euro, start time.Time, months int) Forecast {
+	forecast := Forecast{OpeningBalance: openingBalance, Averages: AveragePerCategory(history, definitions)}
+	balance := openingBalance
+	for idx := range months {
+		month := Month{Start: firstOfMonth(start).AddDate(0, idx, 0)}
+		amounts := slices.Collect(maps.Values(forecast.Averages))
+		for _, tx := range recurring.Expand(definitions, month.Start, month.Start.AddDate(0, 1, -1)) {
+			amounts = append(amounts, tx.Amount)
+		}
+		for _, amount := range amounts {
+			if amount.Cmp(currency.NewEuro(0.0)) > 0 {
+				month.Income = currency.AddEuros(month.Income, amount)
+			} else {
+				month.Expense = currency.AddEuros(month.Expense, amount)
+			}
+		}
+		balance = currency.AddEuros(balance, month.Net())
+		month.Balance = balance
+		forecast.Months = append(forecast.Months, month)
+	}
+	return forecast
+}
+
+// AveragePerCategory returns the average monthly amount of each category in the history, over the months from the
+// first to the last dated transaction. Categories covered by a recurring definition, transfers, investments, generated transactions
+// and transactions whose time is not a date are left out
+func AveragePerCategory(history []transaction.BasicTransaction, definitions []recurring.Definition) map[string]currency.Euro {
+	covered := make(map[string]bool)
+	for _, definition := range definitions {
+		covered[definitionCategory(definition)] = true
+	}
+	totals := make(map[string]currency.Euro)
+	var first, last time.Time
+	for _, tx := range history {
+		date, err := tx.Date()
+		if err != nil || !tx.IsIncomeOrExpense() || tx.Generated {
+			continue
+		}
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		if date.After(last) {
+			last = date
+		}
+		if category := tx.EffectiveCategory(); !covered[category] {
+			totals[category] = currency.AddEuros(totals[category], tx.Amount)
+		}
+	}
+	averages := make(map[string]currency.Euro)
+	if first.IsZero() {
+		return averages
+	}
+	months := 12*(last.Year()-first.Year()) + int(last.Month()-first.Month()) + 1
+	for category, total := range totals {
+		averages[category] = currency.NewEuroFromCents(int64(math.Round(float64(total.Cents()) / float64(months))))
+	}
+	return averages
+}
+
+// definitionCategory returns the category of a recurring definition, falling back to its description like
+// transaction.BasicTransaction.EffectiveCategory
+func definitionCategory(definition recurring.Definition) string {
+	if definition.Category != "" {
+		return definition.Category
+	}
+	return definition.Description
+}
+
+// firstOfMonth returns the first day of the month of the date
+func firstOfMonth(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// FirstNegative returns the first month which ends with a negative balance, and false if the balance stays positive
+func (f Forecast) FirstNegative() (Month, bool) {
+	idx := slices.IndexFunc(f.Months, func(m Month) bool { return m.Balance.Cmp(currency.NewEuro(0.0)) < 0 })
+	if idx < 0 {
+		return Month{}, false
+	}
+	return f.Months[idx], true
+}
+
+// Comparison is a forecast month next to the income and expense which actually happened in it
+type Comparison struct {
+	Forecast      Month
+	ActualIncome  currency.Euro
+	ActualExpense currency.Euro
+}
+
+// ActualNet returns the actual income minus the actual expense of the month
+func (c Comparison) ActualNet() currency.Euro {
+	return currency.AddEuros(c.ActualIncome, c.ActualExpense)
+}
+
+// Difference returns how much the actual net income was above the forecast net income
+func (c Comparison) Difference() currency.Euro {
+	return currency.SubtractEuros(c.ActualNet(), c.Forecast.Net())
+}
+
+// Compare compares the forecast with the actual transactions of every month which closed before asOf. Refunds net against
+// the actual expense, and transfers, investments and transactions whose time is not a date are ignored
+func (f Forecast) Compare(actual []transaction.BasicTransaction, asOf time.Time) []Comparison {
+	var comparisons []Comparison
+	for _, month := range f.Months {
+		// the first day of the next month, as a month only closes once its last day is over
+		end := month.Start.AddDate(0, 1, 0)
+		if asOf.Before(end) {
+			break
+		}
+		comparison := Comparison{Forecast: month}
+		for _, tx := range actual {
+			date, err := tx.Date()
+			if err != nil || date.Before(month.Start) || !date.Before(end) {
+				continue
+			}
+			if tx.IsIncome() {
+				comparison.ActualIncome = currency.AddEuros(comparison.ActualIncome, tx.Amount)
+			} else if tx.IsExpense() {
+				comparison.ActualExpense = currency.AddEuros(comparison.ActualExpense, tx.Amount)
+			}
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// String returns a table of the projected income, expense and end-of-month balance of each month, followed by the
+// month in which the balance would first go negative
+func (f Forecast) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Cash-Flow Forecast (opening balance %s)\n", f.OpeningBalance.String()))
+	str.WriteString("Month,Income,Expense,Net,Balance\n")
+	for _, month := range f.Months {
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", month.Start.Format("2006-01"), month.Income.String(), month.Expense.String(), month.Net().String(), month.Balance.String()))
+	}
+	if month, ok := f.FirstNegative(); ok {
+		str.WriteString(fmt.Sprintf("The balance goes negative at the end of %s (%s)\n", month.Start.Format("2006-01"), month.Balance.String()))
+	}
+	return str.String()
+}
+
+// WriteComparisons writes a table of the forecast and actual net income of each compared month
+func WriteComparisons(str *strings.Builder, comparisons []Comparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+	str.WriteString("Forecast vs Actual\n")
+	str.WriteString("Month,Forecast Net,Actual Net,Difference\n")
+	for _, comparison := range comparisons {
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s\n", comparison.Forecast.Start.Format("2006-01"), comparison.Forecast.Net().String(), comparison.ActualNet().String(), comparison.Difference().String()))
+	}
+}
@@ -0,0 +1,125 @@
+package forecast_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/forecast"
+	"github.com/kevslinger/budget/recurring"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readTransactions(t *testing.T, path string) []transaction.BasicTransaction {
+	payerTransactions, _, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transactions []transaction.BasicTransaction
+	for _, tx := range payerTransactions {
+		transactions = append(transactions, tx.BasicTransaction)
+	}
+	return transactions
+}
+
+func readDefinitions(t *testing.T) []recurring.Definition {
+	definitions, err := recurring.ReadDefinitionsFromFile("../testdata/forecastrecurring.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return definitions
+}
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, value)
+	return parsed
+}
+
+func TestAveragePerCategory(t *testing.T) {
+	history := readTransactions(t, "../testdata/forecasthistory.csv")[:10]
+	averages := forecast.AveragePerCategory(history, readDefinitions(t))
+	expected := map[string]currency.Euro{"Groceries": currency.NewEuro(-300), "Fun": currency.NewEuroFromCents(-3333)}
+	if len(averages) != len(expected) {
+		t.Errorf("Expected averages for %v, got %v", expected, averages)
+	}
+	for category, amount := range expected {
+		if averages[category].Cmp(amount) != 0 {
+			t.Errorf("Expected an average of %s for %s, got %s", amount.String(), category, averages[category].String())
+		}
+	}
+}
+
+func TestForecast(t *testing.T) {
+	history := readTransactions(t, "../testdata/forecasthistory.csv")[:10]
+	f := forecast.New(history, readDefinitions(t), currency.NewEuro(500), date("2025-04-01"), 3)
+	if len(f.Months) != 3 {
+		t.Fatalf("Expected 3 months, got %d", len(f.Months))
+	}
+	// €2500 salary, €900 rent, €1500 car loan, €300 groceries and €33.33 fun each month
+	expectedExpense := currency.NewEuroFromCents(-273333)
+	expectedBalances := []currency.Euro{currency.NewEuroFromCents(26667), currency.NewEuroFromCents(3334), currency.NewEuroFromCents(-19999)}
+	for idx, month := range f.Months {
+		if month.Income.Cmp(currency.NewEuro(2500)) != 0 || month.Expense.Cmp(expectedExpense) != 0 {
+			t.Errorf("Expected income of €2500.00 and expense of %s in %s, got %s and %s", expectedExpense.String(), month.Start.Format("2006-01"), month.Income.String(), month.Expense.String())
+		}
+		if month.Balance.Cmp(expectedBalances[idx]) != 0 {
+			t.Errorf("Expected a balance of %s at the end of %s, got %s", expectedBalances[idx].String(), month.Start.Format("2006-01"), month.Balance.String())
+		}
+	}
+	negative, ok := f.FirstNegative()
+	if !ok || negative.Start.Format("2006-01") != "2025-06" {
+		t.Errorf("Expected the balance to go negative in 2025-06, got %v (%t)", negative.Start, ok)
+	}
+	if !strings.Contains(f.String(), "The balance goes negative at the end of 2025-06") {
+		t.Errorf("Expected the negative balance to be reported, got %s", f.String())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	transactions := readTransactions(t, "../testdata/forecasthistory.csv")
+	f := forecast.New(transactions[:10], readDefinitions(t), currency.NewEuro(500), date("2025-04-01"), 3)
+	comparisons := f.Compare(transactions[10:], date("2025-05-15"))
+	if len(comparisons) != 1 {
+		t.Fatalf("Expected only the closed month to be compared, got %d", len(comparisons))
+	}
+	// the car loan and groceries were forecast but did not happen
+	expected := currency.NewEuroFromCents(183333)
+	if actual := comparisons[0].Difference(); actual.Cmp(expected) != 0 {
+		t.Errorf("Expected a difference of %s, got %s", expected.String(), actual.String())
+	}
+	var str strings.Builder
+	forecast.WriteComparisons(&str, comparisons)
+	if !strings.Contains(str.String(), "2025-04,€-233.33,€1600.00,€1833.33") {
+		t.Errorf("Expected the comparison to be listed, got %s", str.String())
+	}
+}
+
+func TestCompareNetsRefundsAgainstExpenses(t *testing.T) {
+	f := forecast.New(nil, nil, currency.NewEuro(0), date("2025-04-01"), 1)
+	actual := []transaction.BasicTransaction{
+		{Time: "2025-04-02", Amount: currency.NewEuro(1000), Description: "Salary"},
+		{Time: "2025-04-03", Amount: currency.NewEuro(-80), Description: "Shoes", ID: "1"},
+		{Time: "2025-04-10", Amount: currency.NewEuro(30), Description: "Shoes refund", RefundOf: "1"},
+	}
+	comparisons := f.Compare(actual, date("2025-05-01"))
+	if len(comparisons) != 1 {
+		t.Fatalf("Expected one closed month, got %d", len(comparisons))
+	}
+	if comparisons[0].ActualIncome.Cmp(currency.NewEuro(1000)) != 0 || comparisons[0].ActualExpense.Cmp(currency.NewEuro(-50)) != 0 {
+		t.Errorf("Expected the refund to reduce the expense rather than count as income, got %s and %s", comparisons[0].ActualIncome, comparisons[0].ActualExpense)
+	}
+}
+
+func TestCompareWaitsForTheLastDayOfTheMonth(t *testing.T) {
+	f := forecast.New(nil, nil, currency.NewEuro(0), date("2025-04-01"), 1)
+	actual := []transaction.BasicTransaction{{Time: "2025-04-30", Amount: currency.NewEuro(-20), Description: "Groceries"}}
+	if comparisons := f.Compare(actual, date("2025-04-30")); len(comparisons) != 0 {
+		t.Errorf("Expected April not to be closed on its last day, got %d comparison(s)", len(comparisons))
+	}
+	comparisons := f.Compare(actual, date("2025-05-01"))
+	if len(comparisons) != 1 || comparisons[0].ActualExpense.Cmp(currency.NewEuro(-20)) != 0 {
+		t.Errorf("Expected April to include its last day once closed, got %v", comparisons)
+	}
+}
@@ -0,0 +1,207 @@
+package goal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Goal is an amount to save by a date, such as for a holiday, a car or an emergency fund. Money is saved towards the
+// goal either by paying it into an account, or by categorising it, such as a transfer categorised "Savings:Holiday"
+type Goal struct {
+	Name       string
+	Target     currency.Euro
+	TargetDate time.Time
+	// Category links the goal to the expenses in the category or its sub-categories, which count as contributions
+	Category string
+	// Account links the goal to the transactions of the account, whose balance is the amount saved
+	Account string
+	// Start is the first day contributions count from, or the zero time to count every contribution
+	Start time.Time
+}
+
+// ReadGoalsFromFile reads goals from a CSV file with the columns Name,Target,TargetDate and either Category or
+// Account, and optionally Start
+func ReadGoalsFromFile(path string) ([]Goal, error) {
+	var goals []Goal
+	err := csvfile.Read(path, []string{"Name", "Target", "TargetDate"}, func(row csvfile.Row) error {
+		goal := Goal{Name: row.Get("Name"), Category: row.Get("Category"), Account: row.Get("Account")}
+		if goal.Name == "" {
+			return fmt.Errorf("missing goal name")
+		}
+		if (goal.Category == "") == (goal.Account == "") {
+			return fmt.Errorf("goal %q needs either a category or an account", goal.Name)
+		}
+		var err error
+		if goal.Target, err = row.Amount("Target"); err != nil {
+			return err
+		}
+		if goal.Target.Cmp(currency.NewEuro(0.0)) <= 0 {
+			return fmt.Errorf("goal %q needs a positive target", goal.Name)
+		}
+		if goal.TargetDate, err = row.Date("TargetDate"); err != nil {
+			return err
+		}
+		if goal.TargetDate.IsZero() {
+			return fmt.Errorf("missing target date")
+		}
+		if goal.Start, err = row.Date("Start"); err != nil {
+			return err
+		}
+		goals = append(goals, goal)
+		return nil
+	})
+	return goals, err
+}
+
+// Contributions returns the transactions which contribute to the goal, with money saved towards the goal as a
+// positive amount. Transactions whose time is not a date are ignored
+func (g Goal) Contributions(transactions []transaction.BasicTransaction) []transaction.BasicTransaction {
+	var contributions []transaction.BasicTransaction
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || date.Before(g.Start) {
+			continue
+		}
+		if g.Account != "" && tx.Account == g.Account {
+			contributions = append(contributions, tx)
+		} else if category := tx.EffectiveCategory(); g.Category != "" && (category == g.Category || strings.HasPrefix(category, g.Category+report.CategorySeparator)) {
+			tx.Amount = currency.SubtractEuros(currency.NewEuro(0.0), tx.Amount)
+			contributions = append(contributions, tx)
+		}
+	}
+	return contributions
+}
+
+// Progress is how far a goal has come as of a date
+type Progress struct {
+	Goal
+	AsOf  time.Time
+	Saved currency.Euro
+	// RequiredMonthly is how much needs to be saved every month from AsOf to reach the target by the target date
+	RequiredMonthly currency.Euro
+	// MonthlyRate is the average contributed per month since the first contribution
+	MonthlyRate currency.Euro
+	// ProjectedCompletion is when the target is reached at the monthly rate, or the zero time if it never is
+	ProjectedCompletion time.Time
+}
+
+// NewProgress computes the progress of the goal from the transactions up to and including asOf. A goal linked to one
+// of the accounts starts from the account's balance at its start, so that the opening balance counts as saved
+func NewProgress(g Goal, accounts []account.Account, transactions []transaction.BasicTransaction, asOf time.Time) Progress {
+	progress := Progress{Goal: g, AsOf: asOf}
+	if idx := slices.IndexFunc(accounts, func(a account.Account) bool { return a.Name == g.Account }); g.Account != "" && idx >= 0 {
+		a := accounts[idx]
+		if g.Start.After(a.OpeningDate) {
+			progress.Saved = a.BalanceAt(transactions, g.Start.AddDate(0, 0, -1))
+		} else {
+			// transactions before the opening date are already part of the opening balance
+			g.Start = a.OpeningDate
+			progress.Saved = a.OpeningBalance
+		}
+	}
+	var first time.Time
+	contributed := currency.NewEuro(0.0)
+	for _, tx := range g.Contributions(transactions) {
+		date, _ := tx.Date()
+		if date.After(asOf) {
+			continue
+		}
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+		contributed = currency.AddEuros(contributed, tx.Amount)
+	}
+	progress.Saved = currency.AddEuros(progress.Saved, contributed)
+	remaining := progress.Remaining()
+	if remaining.Cents() == 0 {
+		progress.ProjectedCompletion = asOf
+		return progress
+	}
+	progress.RequiredMonthly = currency.NewEuroFromCents(int64(math.Ceil(float64(remaining.Cents()) / float64(max(monthsBetween(asOf, g.TargetDate), 1)))))
+	if first.IsZero() {
+		return progress
+	}
+	progress.MonthlyRate = currency.NewEuroFromCents(contributed.Cents() / int64(monthsBetween(first, asOf)+1))
+	if progress.MonthlyRate.Cents() > 0 {
+		months := int(math.Ceil(float64(remaining.Cents()) / float64(progress.MonthlyRate.Cents())))
+		// the last day of the month the target is reached in
+		progress.ProjectedCompletion = time.Date(asOf.Year(), asOf.Month()+time.Month(months)+1, 0, 0, 0, 0, 0, asOf.Location())
+	}
+	return progress
+}
+
+// monthsBetween returns the number of calendar months from the month of from to the month of to
+func monthsBetween(from, to time.Time) int {
+	return 12*(to.Year()-from.Year()) + int(to.Month()-from.Month())
+}
+
+// Remaining returns how much is left to save, or zero if the target has been reached
+func (p Progress) Remaining() currency.Euro {
+	remaining := currency.SubtractEuros(p.Target, p.Saved)
+	if remaining.Cents() < 0 {
+		return currency.NewEuro(0.0)
+	}
+	return remaining
+}
+
+// PercentComplete returns the share of the target saved so far, which can be above 100%
+func (p Progress) PercentComplete() float64 {
+	return 100 * float64(p.Saved.Cents()) / float64(p.Target.Cents())
+}
+
+// OnTrack reports whether the target is reached by the target date at the monthly rate
+func (p Progress) OnTrack() bool {
+	return !p.ProjectedCompletion.IsZero() && !p.ProjectedCompletion.After(p.TargetDate)
+}
+
+// String describes the amount saved, the contribution required each month, and when the goal will be reached at the
+// current rate
+func (p Progress) String() string {
+	str := fmt.Sprintf("%s: %s of %s saved (%.2f%%) by %s", p.Name, p.Saved.String(), p.Target.String(), p.PercentComplete(), p.TargetDate.Format(time.DateOnly))
+	if p.Remaining().Cents() == 0 {
+		return str + ", reached"
+	}
+	str += fmt.Sprintf(", %s needed per month", p.RequiredMonthly.String())
+	if p.ProjectedCompletion.IsZero() {
+		return str + ", not projected to complete at the current rate"
+	}
+	status := "on track"
+	if !p.OnTrack() {
+		status = "behind"
+	}
+	return str + fmt.Sprintf(", projected to complete %s at %s per month (%s)", p.ProjectedCompletion.Format("2006-01"), p.MonthlyRate.String(), status)
+}
+
+// Report is the progress of every goal as of a date
+type Report struct {
+	AsOf     time.Time
+	Progress []Progress
+}
+
+// NewReport computes the progress of every goal from the accounts and the transactions up to and including asOf
+func NewReport(goals []Goal, accounts []account.Account, transactions []transaction.BasicTransaction, asOf time.Time) Report {
+	r := Report{AsOf: asOf}
+	for _, g := range goals {
+		r.Progress = append(r.Progress, NewProgress(g, accounts, transactions, asOf))
+	}
+	return r
+}
+
+// String lists the progress of every goal
+func (r Report) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Savings Goals as of %s\n", r.AsOf.Format(time.DateOnly)))
+	for _, progress := range r.Progress {
+		str.WriteString(progress.String() + "\n")
+	}
+	return str.String()
+}
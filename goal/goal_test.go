@@ -0,0 +1,90 @@
+package goal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/goal"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readTransactions(t *testing.T) []transaction.BasicTransaction {
+	r, err := report.ReadDefaultBudgetReportFromFile("goals", "../testdata/goaltransactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.Transactions()
+}
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, value)
+	return parsed
+}
+
+func TestProgress(t *testing.T) {
+	goals, err := goal.ReadGoalsFromFile("../testdata/goals.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := goal.NewReport(goals, nil, readTransactions(t), date("2025-03-31"))
+	expected := "Savings Goals as of 2025-03-31\n" +
+		"Holiday: €600.00 of €1200.00 saved (50.00%) by 2025-08-31, €120.00 needed per month, projected to complete 2025-06 at €200.00 per month (on track)\n" +
+		"Emergency fund: €5502.50 of €6000.00 saved (91.71%) by 2025-12-31, €55.28 needed per month, projected to complete 2025-04 at €1834.16 per month (on track)\n" +
+		"Car: €0.00 of €10000.00 saved (0.00%) by 2027-01-31, €454.55 needed per month, not projected to complete at the current rate\n"
+	if actual := r.String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestProgressBehindAndReached(t *testing.T) {
+	transactions := []transaction.BasicTransaction{
+		{Time: "2025-01-10", Amount: currency.NewEuro(-100), Description: "Saving", Category: "Car"},
+		{Time: "2025-02-10", Amount: currency.NewEuro(-100), Description: "Saving", Category: "Car"},
+	}
+	behind := goal.NewProgress(goal.Goal{Name: "Car", Target: currency.NewEuro(1000), TargetDate: date("2025-04-30"), Category: "Car"}, nil, transactions, date("2025-02-28"))
+	if behind.OnTrack() || behind.ProjectedCompletion.Format("2006-01") != "2025-10" {
+		t.Errorf("Expected the goal to be behind and complete in 2025-10, got %s", behind.String())
+	}
+	reached := goal.NewProgress(goal.Goal{Name: "Car", Target: currency.NewEuro(150), TargetDate: date("2025-04-30"), Category: "Car"}, nil, transactions, date("2025-02-28"))
+	if expected := "Car: €200.00 of €150.00 saved (133.33%) by 2025-04-30, reached"; reached.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, reached.String())
+	}
+}
+
+func TestProgressCountsTheOpeningBalanceOfAccountGoals(t *testing.T) {
+	accounts := []account.Account{{Name: "Savings", OpeningBalance: currency.NewEuro(5000), OpeningDate: date("2025-01-01")}}
+	transactions := []transaction.BasicTransaction{
+		{Time: "2025-03-15", Amount: currency.NewEuro(500), Description: "Monthly saving", Account: "Savings"},
+		{Time: "2025-03-31", Amount: currency.NewEuro(2.5), Description: "Interest", Account: "Savings"},
+	}
+	tests := []struct {
+		start    time.Time
+		expected string
+	}{
+		{time.Time{}, "Emergency fund: €5502.50 of €6000.00 saved (91.71%) by 2025-12-31, €55.28 needed per month, projected to complete 2025-04 at €502.50 per month (on track)"},
+		{date("2025-03-20"), "Emergency fund: €5502.50 of €6000.00 saved (91.71%) by 2025-12-31, €55.28 needed per month, projected to complete 2041-10 at €2.50 per month (behind)"},
+	}
+	for _, test := range tests {
+		g := goal.Goal{Name: "Emergency fund", Target: currency.NewEuro(6000), TargetDate: date("2025-12-31"), Account: "Savings", Start: test.start}
+		if actual := goal.NewProgress(g, accounts, transactions, date("2025-03-31")).String(); actual != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestProgressMatchesTheEffectiveCategory(t *testing.T) {
+	transactions := []transaction.BasicTransaction{{Time: "2025-01-10", Amount: currency.NewEuro(-100), Description: "Savings:Car"}}
+	progress := goal.NewProgress(goal.Goal{Name: "Car", Target: currency.NewEuro(1000), TargetDate: date("2025-12-31"), Category: "Savings:Car"}, nil, transactions, date("2025-01-31"))
+	if progress.Saved.Cmp(currency.NewEuro(100)) != 0 {
+		t.Errorf("Expected the uncategorised saving to count by its description, got %s", progress.Saved)
+	}
+}
+
+func TestReadGoalsFromFileNeedsCategoryOrAccount(t *testing.T) {
+	if _, err := goal.ReadGoalsFromFile("../testdata/accounts.csv"); err == nil {
+		t.Errorf("Expected an error reading a file without goal columns")
+	}
+}
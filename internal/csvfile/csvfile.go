@@ -0,0 +1,154 @@
+package csvfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Row is a row of a CSV file whose columns are named by the file's header
+type Row struct {
+	// Line is the line of the file the row starts on
+	Line    int
+	values  []string
+	columns map[string]int
+}
+
+// normalize makes column names comparable, ignoring case and spaces
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", ""))
+}
+
+// Has reports whether the file has the named column
+func (r Row) Has(name string) bool {
+	_, ok := r.columns[normalize(name)]
+	return ok
+}
+
+// Get returns the trimmed value of the named column, or the empty string if the file has no such column
+func (r Row) Get(name string) string {
+	idx, ok := r.columns[normalize(name)]
+	if !ok || idx >= len(r.values) {
+		return ""
+	}
+	return strings.TrimSpace(r.values[idx])
+}
+
+// Amount parses the named column as an amount of euros, where an empty value is zero
+func (r Row) Amount(name string) (currency.Euro, error) {
+	value := r.Get(name)
+	if value == "" {
+		return currency.NewEuro(0), nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return currency.Euro{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return currency.NewEuro(amount), nil
+}
+
+// Float parses the named column as a number, where an empty value is zero
+func (r Row) Float(name string) (float64, error) {
+	value := r.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return number, nil
+}
+
+// Int parses the named column as a whole number, where an empty value is zero
+func (r Row) Int(name string) (int, error) {
+	value := r.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return number, nil
+}
+
+// Bool parses the named column as yes/no, where an empty value is false
+func (r Row) Bool(name string) (bool, error) {
+	value, err := ParseBool(r.Get(name))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: %w", name, r.Get(name), err)
+	}
+	return value, nil
+}
+
+// ParseBool parses a yes/no value, ignoring case and surrounding spaces: y, yes or true, and n, no, false or an empty
+// value
+func ParseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "n", "no", "false":
+		return false, nil
+	case "y", "yes", "true":
+		return true, nil
+	}
+	return false, errors.New("expected yes or no")
+}
+
+// Date parses the named column as a date in one of the transaction.TimeLayouts, where an empty value is the zero time
+func (r Row) Date(name string) (time.Time, error) {
+	value := r.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := transaction.ParseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return date, nil
+}
+
+// Read reads a CSV file with a header row, calling fn with every other row. It returns an error if the header lacks
+// any of the required columns, or fn returns an error, which is annotated with the row's line
+func Read(path string, required []string, fn func(row Row) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	columns := make(map[string]int)
+	for idx, name := range header {
+		columns[normalize(name)] = idx
+	}
+	for _, name := range required {
+		if _, ok := columns[normalize(name)]; !ok {
+			return fmt.Errorf("%s has no %s column", path, name)
+		}
+	}
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if err := fn(Row{Line: line, values: values, columns: columns}); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+	}
+}
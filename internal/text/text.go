@@ -0,0 +1,18 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words returns the lower-cased words of a description, dropping numbers and punctuation such as the reference
+// numbers banks append to descriptions
+func Words(description string) []string {
+	return strings.FieldsFunc(strings.ToLower(description), func(r rune) bool { return !unicode.IsLetter(r) })
+}
+
+// Normalize joins the words of a description with single spaces, so that descriptions such as
+// "REWE SAGT DANKE 1234" and "Rewe sagt danke 5678" compare equal
+func Normalize(description string) string {
+	return strings.Join(Words(description), " ")
+}
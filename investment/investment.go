@@ -0,0 +1,192 @@
+package investment
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+)
+
+// Trade is a buy or, with a negative quantity, a sell of a security
+type Trade struct {
+	Date     time.Time
+	Security string
+	Quantity float64
+	// Price is the price of one unit of the security
+	Price currency.Euro
+	// Fees are paid on top of a buy, or out of the proceeds of a sell
+	Fees currency.Euro
+}
+
+// ReadTradesFromFile reads trades from a CSV file with the columns Date,Security,Quantity,Price and optionally Fees
+func ReadTradesFromFile(path string) ([]Trade, error) {
+	var trades []Trade
+	err := csvfile.Read(path, []string{"Date", "Security", "Quantity", "Price"}, func(row csvfile.Row) error {
+		trade := Trade{Security: row.Get("Security")}
+		if trade.Security == "" {
+			return fmt.Errorf("missing security")
+		}
+		var err error
+		if trade.Date, err = row.Date("Date"); err != nil {
+			return err
+		}
+		if trade.Date.IsZero() {
+			return fmt.Errorf("missing date")
+		}
+		if trade.Quantity, err = row.Float("Quantity"); err != nil {
+			return err
+		}
+		if trade.Quantity == 0 {
+			return fmt.Errorf("trade of %s needs a quantity", trade.Security)
+		}
+		if trade.Price, err = row.Amount("Price"); err != nil {
+			return err
+		}
+		if trade.Fees, err = row.Amount("Fees"); err != nil {
+			return err
+		}
+		trades = append(trades, trade)
+		return nil
+	})
+	return trades, err
+}
+
+// Price is the price of one unit of a security on a day
+type Price struct {
+	Security string
+	Date     time.Time
+	Price    currency.Euro
+}
+
+// Prices are the known prices of securities, from a local price file
+type Prices []Price
+
+// ReadPricesFromFile reads prices from a CSV file with the columns Security,Date,Price
+func ReadPricesFromFile(path string) (Prices, error) {
+	var prices Prices
+	err := csvfile.Read(path, []string{"Security", "Date", "Price"}, func(row csvfile.Row) error {
+		price := Price{Security: row.Get("Security")}
+		var err error
+		if price.Date, err = row.Date("Date"); err != nil {
+			return err
+		}
+		if price.Price, err = row.Amount("Price"); err != nil {
+			return err
+		}
+		prices = append(prices, price)
+		return nil
+	})
+	return prices, err
+}
+
+// Latest returns the most recent price of the security on or before the date, and false if there is none
+func (p Prices) Latest(security string, date time.Time) (Price, bool) {
+	var latest Price
+	found := false
+	for _, price := range p {
+		if price.Security != security || price.Date.After(date) {
+			continue
+		}
+		if !found || !price.Date.Before(latest.Date) {
+			latest, found = price, true
+		}
+	}
+	return latest, found
+}
+
+// Holding is the quantity of a security held, what it cost and what it is worth
+type Holding struct {
+	Security string
+	Quantity float64
+	// CostBasis is what the units still held cost, including fees, using the average cost of every unit bought
+	CostBasis currency.Euro
+	// RealisedGain is the proceeds of the units sold minus what they cost
+	RealisedGain currency.Euro
+	// Price is the latest known price, and PriceDate is the zero time if no price is known
+	Price     currency.Euro
+	PriceDate time.Time
+}
+
+// Value returns the quantity held at the latest known price
+func (h Holding) Value() currency.Euro {
+	return currency.NewEuroFromCents(int64(math.Round(h.Quantity * float64(h.Price.Cents()))))
+}
+
+// UnrealisedGain returns how much more the holding is worth than it cost
+func (h Holding) UnrealisedGain() currency.Euro {
+	return currency.SubtractEuros(h.Value(), h.CostBasis)
+}
+
+// Holdings applies the trades up to and including asOf, returning the holding of every security, sorted by security.
+// It returns an error if a security is sold before enough units of it are bought
+func Holdings(trades []Trade, prices Prices, asOf time.Time) ([]Holding, error) {
+	sorted := slices.Clone(trades)
+	slices.SortStableFunc(sorted, func(a, b Trade) int { return a.Date.Compare(b.Date) })
+	holdings := make(map[string]*Holding)
+	for _, trade := range sorted {
+		if trade.Date.After(asOf) {
+			break
+		}
+		holding, ok := holdings[trade.Security]
+		if !ok {
+			holding = &Holding{Security: trade.Security}
+			holdings[trade.Security] = holding
+		}
+		amount := int64(math.Round(math.Abs(trade.Quantity) * float64(trade.Price.Cents())))
+		if trade.Quantity > 0 {
+			holding.CostBasis = currency.AddEuros(holding.CostBasis, currency.NewEuroFromCents(amount+trade.Fees.Cents()))
+			holding.Quantity += trade.Quantity
+			continue
+		}
+		// allow for rounding in the quantities of fractional shares
+		if -trade.Quantity > holding.Quantity+1e-9 {
+			return nil, fmt.Errorf("sold %g units of %s on %s, but only %g are held", -trade.Quantity, trade.Security, trade.Date.Format(time.DateOnly), holding.Quantity)
+		}
+		soldCost := currency.NewEuroFromCents(int64(math.Round(float64(holding.CostBasis.Cents()) * -trade.Quantity / holding.Quantity)))
+		proceeds := currency.NewEuroFromCents(amount - trade.Fees.Cents())
+		holding.RealisedGain = currency.AddEuros(holding.RealisedGain, currency.SubtractEuros(proceeds, soldCost))
+		holding.CostBasis = currency.SubtractEuros(holding.CostBasis, soldCost)
+		holding.Quantity += trade.Quantity
+	}
+	var result []Holding
+	for _, security := range slices.Sorted(maps.Keys(holdings)) {
+		holding := *holdings[security]
+		if price, ok := prices.Latest(security, asOf); ok {
+			holding.Price, holding.PriceDate = price.Price, price.Date
+		}
+		result = append(result, holding)
+	}
+	return result, nil
+}
+
+// Report is the value, cost basis and gains of every holding as of a date
+type Report struct {
+	AsOf     time.Time
+	Holdings []Holding
+}
+
+// String returns a table of the holdings followed by their totals. Holdings without a known price are valued at zero
+func (r Report) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Investment Holdings as of %s\n", r.AsOf.Format(time.DateOnly)))
+	str.WriteString("Security,Quantity,Price,Value,Cost Basis,Unrealised Gain,Realised Gain\n")
+	var value, costBasis, unrealised, realised currency.Euro
+	for _, holding := range r.Holdings {
+		price := holding.Price.String()
+		if holding.PriceDate.IsZero() {
+			price = "no price"
+		}
+		str.WriteString(fmt.Sprintf("%s,%g,%s,%s,%s,%s,%s\n", holding.Security, holding.Quantity, price, holding.Value().String(), holding.CostBasis.String(), holding.UnrealisedGain().String(), holding.RealisedGain.String()))
+		value = currency.AddEuros(value, holding.Value())
+		costBasis = currency.AddEuros(costBasis, holding.CostBasis)
+		unrealised = currency.AddEuros(unrealised, holding.UnrealisedGain())
+		realised = currency.AddEuros(realised, holding.RealisedGain)
+	}
+	str.WriteString(fmt.Sprintf("Total,,,%s,%s,%s,%s\n", value.String(), costBasis.String(), unrealised.String(), realised.String()))
+	return str.String()
+}
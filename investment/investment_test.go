@@ -0,0 +1,51 @@
+package investment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/investment"
+)
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, value)
+	return parsed
+}
+
+func TestHoldings(t *testing.T) {
+	trades, err := investment.ReadTradesFromFile("../testdata/trades.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices, err := investment.ReadPricesFromFile("../testdata/prices.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holdings, err := investment.Holdings(trades, prices, date("2025-03-31"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Investment Holdings as of 2025-03-31\n" +
+		"Security,Quantity,Price,Value,Cost Basis,Unrealised Gain,Realised Gain\n" +
+		"AAPL,2,no price,€0.00,€400.00,€-400.00,€0.00\n" +
+		"VWCE,15,€125.00,€1875.00,€1576.50,€298.50,€73.50\n" +
+		"Total,,,€1875.00,€1976.50,€-101.50,€73.50\n"
+	if actual := (investment.Report{AsOf: date("2025-03-31"), Holdings: holdings}).String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+	earlier, err := investment.Holdings(trades, prices, date("2025-03-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vwce := earlier[1]; vwce.Quantity != 20 || vwce.Price.Cmp(currency.NewEuro(108)) != 0 {
+		t.Errorf("Expected 20 units at the February price, got %+v", vwce)
+	}
+}
+
+func TestHoldingsOversold(t *testing.T) {
+	trades := []investment.Trade{{Date: date("2025-01-01"), Security: "VWCE", Quantity: 1, Price: currency.NewEuro(100)}, {Date: date("2025-01-02"), Security: "VWCE", Quantity: -2, Price: currency.NewEuro(100)}}
+	if _, err := investment.Holdings(trades, nil, date("2025-01-31")); err == nil {
+		t.Errorf("Expected an error selling more units than are held")
+	}
+}
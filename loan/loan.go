@@ -0,0 +1,313 @@
+package loan
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// ExtraPayment is a one-off payment towards a loan's principal on top of the monthly payment
+type ExtraPayment struct {
+	Date   time.Time
+	Amount currency.Euro
+}
+
+// Loan is an annuity loan, such as a mortgage or a car loan, repaid with a fixed monthly payment
+type Loan struct {
+	Name      string
+	Principal currency.Euro
+	// Rate is the yearly interest rate as a percentage, e.g. 3.5
+	Rate       float64
+	TermMonths int
+	// Start is the day the loan was paid out. The first payment is due a month later
+	Start time.Time
+	// ExtraMonthly is paid towards the principal on top of every monthly payment
+	ExtraMonthly  currency.Euro
+	ExtraPayments []ExtraPayment
+	// Match is the text which the description or payee of a payment transaction contains, ignoring case
+	Match string
+}
+
+// ReadLoansFromFile reads loans from a CSV file with the columns Name,Principal,Rate,TermMonths,Start and optionally
+// Extra, the extra monthly payment, and Match, which defaults to the name
+func ReadLoansFromFile(path string) ([]Loan, error) {
+	var loans []Loan
+	err := csvfile.Read(path, []string{"Name", "Principal", "Rate", "TermMonths", "Start"}, func(row csvfile.Row) error {
+		loan := Loan{Name: row.Get("Name"), Match: row.Get("Match")}
+		if loan.Name == "" {
+			return fmt.Errorf("missing loan name")
+		}
+		if loan.Match == "" {
+			loan.Match = loan.Name
+		}
+		var err error
+		if loan.Principal, err = row.Amount("Principal"); err != nil {
+			return err
+		}
+		if loan.Rate, err = row.Float("Rate"); err != nil {
+			return err
+		}
+		if loan.TermMonths, err = row.Int("TermMonths"); err != nil {
+			return err
+		}
+		if loan.Principal.Cmp(currency.NewEuro(0.0)) <= 0 || loan.Rate < 0 || loan.TermMonths < 1 {
+			return fmt.Errorf("loan %q needs a positive principal and term, and a rate of at least zero", loan.Name)
+		}
+		if loan.Start, err = row.Date("Start"); err != nil {
+			return err
+		}
+		if loan.Start.IsZero() {
+			return fmt.Errorf("missing start date")
+		}
+		if loan.ExtraMonthly, err = row.Amount("Extra"); err != nil {
+			return err
+		}
+		loans = append(loans, loan)
+		return nil
+	})
+	return loans, err
+}
+
+// ReadExtraPaymentsFromFile adds the one-off extra payments in a CSV file with the columns Loan,Date,Amount to the
+// loans. It returns an error if a payment is for an unknown loan
+func ReadExtraPaymentsFromFile(path string, loans []Loan) error {
+	return csvfile.Read(path, []string{"Loan", "Date", "Amount"}, func(row csvfile.Row) error {
+		idx := slices.IndexFunc(loans, func(l Loan) bool { return l.Name == row.Get("Loan") })
+		if idx < 0 {
+			return fmt.Errorf("extra payment for unknown loan %q", row.Get("Loan"))
+		}
+		payment := ExtraPayment{}
+		var err error
+		if payment.Date, err = row.Date("Date"); err != nil {
+			return err
+		}
+		if payment.Amount, err = row.Amount("Amount"); err != nil {
+			return err
+		}
+		loans[idx].ExtraPayments = append(loans[idx].ExtraPayments, payment)
+		return nil
+	})
+}
+
+// MonthlyPayment returns the fixed payment which repays the principal with interest over the term, excluding extra
+// payments
+func (l Loan) MonthlyPayment() currency.Euro {
+	monthlyRate := l.Rate / 100 / 12
+	if monthlyRate == 0 {
+		return currency.NewEuroFromCents(int64(math.Ceil(float64(l.Principal.Cents()) / float64(l.TermMonths))))
+	}
+	payment := monthlyRate * float64(l.Principal.Cents()) / (1 - math.Pow(1+monthlyRate, -float64(l.TermMonths)))
+	return currency.NewEuroFromCents(int64(math.Round(payment)))
+}
+
+// WithExtraMonthly returns a copy of the loan with a different extra monthly payment, to see what overpaying would do
+func (l Loan) WithExtraMonthly(extra currency.Euro) Loan {
+	l.ExtraMonthly = extra
+	return l
+}
+
+// Payment is one month of an amortisation schedule
+type Payment struct {
+	Number    int
+	Date      time.Time
+	Interest  currency.Euro
+	Principal currency.Euro
+	// Balance is what remains to be repaid after the payment
+	Balance currency.Euro
+}
+
+// Total returns the interest and principal paid
+func (p Payment) Total() currency.Euro {
+	return currency.AddEuros(p.Interest, p.Principal)
+}
+
+// Schedule returns the monthly payments until the loan is repaid. Interest is charged on the remaining balance each
+// month, and extra payments reduce the principal, shortening the term
+func (l Loan) Schedule() []Payment {
+	monthlyRate := l.Rate / 100 / 12
+	payment := l.MonthlyPayment()
+	balance := l.Principal
+	var schedule []Payment
+	for number := 1; balance.Cents() > 0; number++ {
+		date := l.dueDate(number)
+		interest := currency.NewEuroFromCents(int64(math.Round(float64(balance.Cents()) * monthlyRate)))
+		principal := currency.AddEuros(currency.SubtractEuros(payment, interest), l.ExtraMonthly)
+		for _, extra := range l.ExtraPayments {
+			if !extra.Date.After(date) && extra.Date.After(l.dueDate(number-1)) {
+				principal = currency.AddEuros(principal, extra.Amount)
+			}
+		}
+		if principal.Cmp(balance) > 0 || number >= l.TermMonths {
+			principal = balance
+		}
+		balance = currency.SubtractEuros(balance, principal)
+		schedule = append(schedule, Payment{Number: number, Date: date, Interest: interest, Principal: principal, Balance: balance})
+	}
+	return schedule
+}
+
+// dueDate returns the date of the numbered payment, where payment zero is the start of the loan
+func (l Loan) dueDate(number int) time.Time {
+	month := time.Date(l.Start.Year(), l.Start.Month()+time.Month(number), 1, 0, 0, 0, 0, l.Start.Location())
+	lastDay := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(l.Start.Day(), lastDay)-1)
+}
+
+// TotalInterest returns the interest paid over a schedule
+func TotalInterest(schedule []Payment) currency.Euro {
+	total := currency.NewEuro(0.0)
+	for _, payment := range schedule {
+		total = currency.AddEuros(total, payment.Interest)
+	}
+	return total
+}
+
+// ScheduledBalance returns the balance the schedule says remains at the end of a day
+func (l Loan) ScheduledBalance(date time.Time) currency.Euro {
+	balance := l.Principal
+	for _, payment := range l.Schedule() {
+		if payment.Date.After(date) {
+			break
+		}
+		balance = payment.Balance
+	}
+	return balance
+}
+
+// matches reports whether the transaction is a payment of the loan
+func (l Loan) matches(tx transaction.BasicTransaction) bool {
+	match := strings.ToLower(l.Match)
+	return tx.Amount.Cmp(currency.NewEuro(0.0)) < 0 && (strings.Contains(strings.ToLower(tx.Description), match) || strings.Contains(strings.ToLower(tx.Payee), match))
+}
+
+// InterestCategory returns the category of the part of the loan's payments which pays interest
+func (l Loan) InterestCategory() string {
+	return strings.Join([]string{"Loan", l.Name, "Interest"}, report.CategorySeparator)
+}
+
+// PrincipalCategory returns the category of the part of the loan's payments which repays the principal
+func (l Loan) PrincipalCategory() string {
+	return strings.Join([]string{"Loan", l.Name, "Principal"}, report.CategorySeparator)
+}
+
+// Split replaces every payment of the loan among the transactions with an interest and a principal transaction. The
+// interest is taken from the schedule payment due in the same month, and the rest of the payment repays the principal.
+// The interest of a month is only charged once, so further payments in the same month only repay the principal.
+// Payments in months without a scheduled payment, and other transactions, are kept as they are
+func (l Loan) Split(transactions []transaction.BasicTransaction) []transaction.BasicTransaction {
+	payerTransactions := make([]transaction.PayerTransaction, len(transactions))
+	for idx, tx := range transactions {
+		payerTransactions[idx].BasicTransaction = tx
+	}
+	var split []transaction.BasicTransaction
+	for _, tx := range l.SplitPayers(payerTransactions) {
+		split = append(split, tx.BasicTransaction)
+	}
+	return split
+}
+
+// SplitPayers is Split for transactions which were paid by someone, keeping who paid each part of a payment
+func (l Loan) SplitPayers(transactions []transaction.PayerTransaction) []transaction.PayerTransaction {
+	schedule := l.Schedule()
+	// charged is the interest already taken from earlier payments, per schedule payment
+	charged := make(map[int]currency.Euro)
+	var split []transaction.PayerTransaction
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || !l.matches(tx.BasicTransaction) {
+			split = append(split, tx)
+			continue
+		}
+		idx := slices.IndexFunc(schedule, func(p Payment) bool {
+			return p.Date.Year() == date.Year() && p.Date.Month() == date.Month()
+		})
+		if idx < 0 {
+			split = append(split, tx)
+			continue
+		}
+		interest := currency.SubtractEuros(charged[idx], schedule[idx].Interest)
+		if interest.Cmp(tx.Amount) < 0 {
+			interest = tx.Amount
+		}
+		charged[idx] = currency.SubtractEuros(charged[idx], interest)
+		interestTx, principalTx := tx, tx
+		interestTx.Amount, interestTx.Category = interest, l.InterestCategory()
+		principalTx.Amount, principalTx.Category = currency.SubtractEuros(tx.Amount, interest), l.PrincipalCategory()
+		if tx.ID != "" {
+			interestTx.ID, principalTx.ID = tx.ID+"-interest", tx.ID+"-principal"
+		}
+		if interestTx.Amount.Cents() != 0 {
+			split = append(split, interestTx)
+		}
+		if principalTx.Amount.Cents() != 0 {
+			split = append(split, principalTx)
+		}
+	}
+	return split
+}
+
+// RemainingBalance returns the principal minus the principal repaid by the transactions, which must have been split by
+// Split
+func (l Loan) RemainingBalance(transactions []transaction.BasicTransaction) currency.Euro {
+	balance := l.Principal
+	for _, tx := range transactions {
+		if tx.Category == l.PrincipalCategory() {
+			balance = currency.AddEuros(balance, tx.Amount)
+		}
+	}
+	return balance
+}
+
+// Report describes a loan's payment, payoff and remaining balance, and optionally what overpaying every month would do
+type Report struct {
+	Loan
+	AsOf time.Time
+	// Remaining is the balance after the payments among the transactions, and Scheduled the balance the schedule expects
+	Remaining currency.Euro
+	Scheduled currency.Euro
+	Schedule  []Payment
+	// WhatIf is the schedule when paying WhatIfExtra more every month, if it is not zero
+	WhatIfExtra currency.Euro
+	WhatIf      []Payment
+}
+
+// NewReport describes the loan as of a date, from transactions which have been split by Split
+func NewReport(l Loan, split []transaction.BasicTransaction, asOf time.Time, whatIfExtra currency.Euro) Report {
+	r := Report{Loan: l, AsOf: asOf, Remaining: l.RemainingBalance(split), Scheduled: l.ScheduledBalance(asOf), Schedule: l.Schedule(), WhatIfExtra: whatIfExtra}
+	if whatIfExtra.Cents() != 0 {
+		r.WhatIf = l.WithExtraMonthly(currency.AddEuros(l.ExtraMonthly, whatIfExtra)).Schedule()
+	}
+	return r
+}
+
+// String describes the loan's payment, remaining balance and payoff, followed by the overpayment scenario
+func (r Report) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Loan %s: %s at %.2f%% over %d months from %s, %s per month", r.Name, r.Principal.String(), r.Rate, r.TermMonths, r.Start.Format(time.DateOnly), r.MonthlyPayment().String()))
+	if r.ExtraMonthly.Cents() != 0 {
+		str.WriteString(fmt.Sprintf(" plus %s extra", r.ExtraMonthly.String()))
+	}
+	str.WriteString("\n")
+	str.WriteString(fmt.Sprintf("Remaining balance on %s: %s (scheduled %s)\n", r.AsOf.Format(time.DateOnly), r.Remaining.String(), r.Scheduled.String()))
+	str.WriteString(fmt.Sprintf("Paid off %s, total interest %s\n", r.Schedule[len(r.Schedule)-1].Date.Format("2006-01"), TotalInterest(r.Schedule).String()))
+	if len(r.WhatIf) > 0 {
+		saving := currency.SubtractEuros(TotalInterest(r.Schedule), TotalInterest(r.WhatIf))
+		str.WriteString(fmt.Sprintf("With %s more per month: paid off %s, %d month(s) sooner, total interest %s, saving %s\n", r.WhatIfExtra.String(), r.WhatIf[len(r.WhatIf)-1].Date.Format("2006-01"), len(r.Schedule)-len(r.WhatIf), TotalInterest(r.WhatIf).String(), saving.String()))
+	}
+	return str.String()
+}
+
+// WriteSchedule writes the amortisation schedule as a table
+func WriteSchedule(str *strings.Builder, schedule []Payment) {
+	str.WriteString("Number,Date,Payment,Interest,Principal,Balance\n")
+	for _, payment := range schedule {
+		str.WriteString(fmt.Sprintf("%d,%s,%s,%s,%s,%s\n", payment.Number, payment.Date.Format(time.DateOnly), payment.Total().String(), payment.Interest.String(), payment.Principal.String(), payment.Balance.String()))
+	}
+}
@@ -0,0 +1,119 @@
+package loan_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/loan"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, value)
+	return parsed
+}
+
+func readLoan(t *testing.T) loan.Loan {
+	loans, err := loan.ReadLoansFromFile("../testdata/loans.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loans) != 1 {
+		t.Fatalf("Expected 1 loan, got %d", len(loans))
+	}
+	return loans[0]
+}
+
+func TestSchedule(t *testing.T) {
+	l := readLoan(t)
+	if payment := l.MonthlyPayment(); payment.Cmp(currency.NewEuroFromCents(86066)) != 0 {
+		t.Errorf("Expected a monthly payment of €860.66, got %s", payment.String())
+	}
+	schedule := l.Schedule()
+	if len(schedule) != 12 {
+		t.Fatalf("Expected 12 payments, got %d", len(schedule))
+	}
+	first, last := schedule[0], schedule[len(schedule)-1]
+	if first.Date.Format(time.DateOnly) != "2025-02-15" || first.Interest.Cmp(currency.NewEuro(50)) != 0 || first.Principal.Cmp(currency.NewEuroFromCents(81066)) != 0 {
+		t.Errorf("Expected the first payment on 2025-02-15 of €50.00 interest and €810.66 principal, got %+v", first)
+	}
+	if last.Date.Format(time.DateOnly) != "2026-01-15" || last.Balance.Cents() != 0 {
+		t.Errorf("Expected the loan to be repaid on 2026-01-15, got %+v", last)
+	}
+	repaid := currency.NewEuro(0.0)
+	for _, payment := range schedule {
+		repaid = currency.AddEuros(repaid, payment.Principal)
+	}
+	if repaid.Cmp(l.Principal) != 0 {
+		t.Errorf("Expected the principal of %s to be repaid, got %s", l.Principal.String(), repaid.String())
+	}
+}
+
+func TestOverpayment(t *testing.T) {
+	l := readLoan(t)
+	overpaid := l.WithExtraMonthly(currency.NewEuro(200)).Schedule()
+	if len(overpaid) != 10 {
+		t.Errorf("Expected overpaying €200.00 per month to repay the loan in 10 months, got %d", len(overpaid))
+	}
+	if loan.TotalInterest(overpaid).Cmp(loan.TotalInterest(l.Schedule())) >= 0 {
+		t.Errorf("Expected overpaying to save interest")
+	}
+	l.ExtraPayments = []loan.ExtraPayment{{Date: date("2025-03-01"), Amount: currency.NewEuro(5000)}}
+	schedule := l.Schedule()
+	if schedule[1].Principal.Cmp(currency.NewEuro(5000)) <= 0 || len(schedule) >= 12 {
+		t.Errorf("Expected the one-off payment to be added to the second payment and shorten the term, got %+v", schedule[1])
+	}
+}
+
+func TestSplit(t *testing.T) {
+	l := readLoan(t)
+	r, err := report.ReadDefaultBudgetReportFromFile("loan", "../testdata/loantransactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := l.Split(r.Transactions())
+	if len(split) != 5 {
+		t.Fatalf("Expected both payments to be split, got %v", split)
+	}
+	expected := []transaction.BasicTransaction{
+		{Time: "2025-02-15", Amount: currency.NewEuro(-50), Description: "Car loan payment", Category: "Loan:Car:Interest"},
+		{Time: "2025-02-15", Amount: currency.NewEuroFromCents(-81066), Description: "Car loan payment", Category: "Loan:Car:Principal"},
+	}
+	for idx, tx := range expected {
+		if split[idx].Amount.Cmp(tx.Amount) != 0 || split[idx].Category != tx.Category {
+			t.Errorf("Expected %+v, got %+v", tx, split[idx])
+		}
+	}
+	remaining := l.RemainingBalance(split)
+	if scheduled := l.ScheduledBalance(date("2025-03-31")); remaining.Cmp(scheduled) != 0 {
+		t.Errorf("Expected the remaining balance %s to match the schedule's %s", remaining.String(), scheduled.String())
+	}
+	str := loan.NewReport(l, split, date("2025-03-31"), currency.NewEuro(200)).String()
+	if !strings.Contains(str, "Loan Car: €10000.00 at 6.00% over 12 months from 2025-01-15, €860.66 per month\n") || !strings.Contains(str, "With €200.00 more per month: paid off 2025-11, 2 month(s) sooner") {
+		t.Errorf("Expected the loan and the overpayment scenario, got %s", str)
+	}
+}
+
+func TestSplitChargesInterestOncePerMonth(t *testing.T) {
+	l := readLoan(t)
+	split := l.Split([]transaction.BasicTransaction{
+		{Time: "2025-02-15", Amount: currency.NewEuroFromCents(-86066), Description: "Car loan payment"},
+		{Time: "2025-02-20", Amount: currency.NewEuro(-500), Description: "Car loan payment"},
+	})
+	expected := []transaction.BasicTransaction{
+		{Time: "2025-02-15", Amount: currency.NewEuro(-50), Description: "Car loan payment", Category: "Loan:Car:Interest"},
+		{Time: "2025-02-15", Amount: currency.NewEuroFromCents(-81066), Description: "Car loan payment", Category: "Loan:Car:Principal"},
+		{Time: "2025-02-20", Amount: currency.NewEuro(-500), Description: "Car loan payment", Category: "Loan:Car:Principal"},
+	}
+	if len(split) != len(expected) {
+		t.Fatalf("Expected the second payment to only repay the principal, got %v", split)
+	}
+	for idx, tx := range expected {
+		if split[idx].Time != tx.Time || split[idx].Amount.Cmp(tx.Amount) != 0 || split[idx].Category != tx.Category {
+			t.Errorf("Expected %+v, got %+v", tx, split[idx])
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// LocksSuffix replaces the extension of a report file to name the file recording its reconciled periods, so that
+// "march.csv" is locked by "march.reconciled.csv". It has the same Account,Date,Balance columns as a balance
+// assertions file
+const LocksSuffix = ".reconciled.csv"
+
+// Reconciliation compares the cleared transactions of an account with the closing balance of a bank statement
+type Reconciliation struct {
+	Account          string
+	StatementDate    time.Time
+	StatementBalance currency.Euro
+	// ClearedBalance is the opening balance plus the cleared transactions up to the statement date
+	ClearedBalance currency.Euro
+	// Uncleared are the transactions of the account up to the statement date which have not been cleared
+	Uncleared []transaction.BasicTransaction
+}
+
+// New reconciles the transactions of an account up to the statement date with the statement's closing balance
+func New(a account.Account, transactions []transaction.BasicTransaction, date time.Time, balance currency.Euro) Reconciliation {
+	var cleared []transaction.BasicTransaction
+	r := Reconciliation{Account: a.Name, StatementDate: date, StatementBalance: balance}
+	for _, tx := range transactions {
+		txDate, err := tx.Date()
+		if err != nil || tx.Account != a.Name || txDate.Before(a.OpeningDate) || txDate.After(date) {
+			continue
+		}
+		if tx.Cleared {
+			cleared = append(cleared, tx)
+		} else {
+			r.Uncleared = append(r.Uncleared, tx)
+		}
+	}
+	r.ClearedBalance = a.BalanceAt(cleared, date)
+	return r
+}
+
+// Difference returns the cleared balance minus the statement balance, which is zero once the account is reconciled
+func (r Reconciliation) Difference() currency.Euro {
+	return currency.SubtractEuros(r.ClearedBalance, r.StatementBalance)
+}
+
+// Balanced reports whether the cleared balance matches the statement balance
+func (r Reconciliation) Balanced() bool {
+	return r.Difference().Cents() == 0
+}
+
+// String describes the statement and cleared balances, their difference and the uncleared transactions
+func (r Reconciliation) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Reconciliation of %s on %s\n", r.Account, r.StatementDate.Format(time.DateOnly)))
+	str.WriteString(fmt.Sprintf("Statement Balance: %s\n", r.StatementBalance.String()))
+	str.WriteString(fmt.Sprintf("Cleared Balance: %s\n", r.ClearedBalance.String()))
+	str.WriteString(fmt.Sprintf("Difference: %s\n", r.Difference().String()))
+	str.WriteString(fmt.Sprintf("%d uncleared transaction(s)\n", len(r.Uncleared)))
+	for _, tx := range r.Uncleared {
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s\n", tx.Time, tx.ID, tx.Amount.String(), tx.Description))
+	}
+	return str.String()
+}
+
+// Clear marks the transactions of an account up to a date as cleared, either those with the given IDs or, if there are
+// none, all of them. The child lines of a cleared split transaction are cleared with it. It returns an error if an ID
+// is not one of those transactions
+func Clear(transactions []transaction.PayerTransaction, accountName string, date time.Time, ids []string) error {
+	found := make(map[string]bool)
+	for i, tx := range transactions {
+		txDate, err := tx.Date()
+		if err != nil || tx.Account != accountName || txDate.After(date) {
+			continue
+		}
+		if len(ids) == 0 || slices.Contains(ids, tx.ID) {
+			transactions[i].Cleared = true
+			found[tx.ID] = true
+		}
+	}
+	for i, tx := range transactions {
+		if tx.Parent != "" && found[tx.Parent] {
+			transactions[i].Cleared = true
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("no transaction of %s up to %s with ID %q", accountName, date.Format(time.DateOnly), id)
+		}
+	}
+	return nil
+}
+
+// LocksPath returns the path of the file recording the reconciled periods of a report file
+func LocksPath(reportPath string) string {
+	return strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + LocksSuffix
+}
+
+// ReadLocks reads the reconciled periods of a report file, which has none if it has no locks file
+func ReadLocks(reportPath string) ([]account.Assertion, error) {
+	locks, err := account.ReadAssertionsFromFile(LocksPath(reportPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return locks, err
+}
+
+// Lock records the statement of a balanced reconciliation in the locks file of a report file, so the transactions of the account up
+// to the statement date can no longer be edited
+func Lock(reportPath string, r Reconciliation) error {
+	if !r.Balanced() {
+		return fmt.Errorf("cannot lock %s on %s: the cleared balance differs from the statement balance by %s", r.Account, r.StatementDate.Format(time.DateOnly), r.Difference().String())
+	}
+	path := LocksPath(reportPath)
+	_, err := os.Stat(path)
+	newFile := errors.Is(err, fs.ErrNotExist)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening reconciled periods file: %w", err)
+	}
+	defer file.Close()
+	if newFile {
+		fmt.Fprintln(file, "Account,Date,Balance")
+	}
+	if _, err := fmt.Fprintf(file, "%s,%s,%.2f\n", r.Account, r.StatementDate.Format(time.DateOnly), float64(r.StatementBalance.Cents())/100); err != nil {
+		return fmt.Errorf("error writing reconciled periods file: %w", err)
+	}
+	return nil
+}
+
+// locked returns the transactions in the reconciled periods, encoded so they can be compared
+func locked(transactions []transaction.PayerTransaction, locks []account.Assertion) ([]string, error) {
+	var encoded []string
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil {
+			continue
+		}
+		if !slices.ContainsFunc(locks, func(lock account.Assertion) bool { return lock.Account == tx.Account && !date.After(lock.Date) }) {
+			continue
+		}
+		data, err := json.Marshal(tx)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing reconciled transactions: %w", err)
+		}
+		encoded = append(encoded, string(data))
+	}
+	slices.Sort(encoded)
+	return encoded, nil
+}
+
+// CheckSave returns an error if saving the transactions to a report file would add, remove or change transactions in
+// its reconciled periods. There is nothing to check if the file does not exist yet
+func CheckSave(path string, transactions []transaction.PayerTransaction) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	locks, err := ReadLocks(path)
+	if err != nil || len(locks) == 0 {
+		return err
+	}
+	existing, _, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		return err
+	}
+	before, err := locked(existing, locks)
+	if err != nil {
+		return err
+	}
+	after, err := locked(transactions, locks)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(before, after) {
+		return fmt.Errorf("%s would change transactions in a reconciled period, as recorded in %s", path, LocksPath(path))
+	}
+	return nil
+}
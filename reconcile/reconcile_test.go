@@ -0,0 +1,121 @@
+package reconcile_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/account"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/reconcile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+var checking = account.Account{Name: "Checking", OpeningBalance: currency.NewEuro(1000), OpeningDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+var statementDate = time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+func readTransactions(t *testing.T, path string) []transaction.PayerTransaction {
+	transactions, _, err := report.ReadTransactionsFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transactions
+}
+
+func basic(transactions []transaction.PayerTransaction) []transaction.BasicTransaction {
+	var basic []transaction.BasicTransaction
+	for _, tx := range transactions {
+		basic = append(basic, tx.BasicTransaction)
+	}
+	return basic
+}
+
+func TestReconciliation(t *testing.T) {
+	transactions := readTransactions(t, "../testdata/reconcile.csv")
+	r := reconcile.New(checking, basic(transactions), statementDate, currency.NewEuro(3054.50))
+	expected := "Reconciliation of Checking on 2025-03-31\n" +
+		"Statement Balance: €3054.50\n" +
+		"Cleared Balance: €3100.00\n" +
+		"Difference: €45.50\n" +
+		"2 uncleared transaction(s)\n" +
+		"2025-03-20,tx-3,€-45.50,Groceries\n" +
+		"2025-03-30,tx-4,€-20.00,Cheque to plumber\n"
+	if actual := r.String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+	if err := reconcile.Clear(transactions, "Checking", statementDate, []string{"tx-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if r = reconcile.New(checking, basic(transactions), statementDate, currency.NewEuro(3054.50)); !r.Balanced() || len(r.Uncleared) != 1 {
+		t.Errorf("Expected the account to be reconciled with the cheque outstanding, got\n%s", r.String())
+	}
+	if err := reconcile.Clear(transactions, "Checking", statementDate, []string{"tx-5"}); err == nil {
+		t.Error("Expected an error clearing a transaction after the statement date")
+	}
+}
+
+func TestClearSplitTransaction(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-10", Amount: currency.NewEuro(-100), Description: "Supermarket", ID: "tx-1", Account: "Checking"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-10", Amount: currency.NewEuro(-60), Description: "Supermarket", Category: "Groceries", Account: "Checking", Parent: "tx-1"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-10", Amount: currency.NewEuro(-40), Description: "Supermarket", Category: "Household", Account: "Checking", Parent: "tx-1"}},
+	}
+	if err := reconcile.Clear(transactions, "Checking", statementDate, []string{"tx-1"}); err != nil {
+		t.Fatal(err)
+	}
+	r := reconcile.New(checking, report.SplitLines(basic(transactions)), statementDate, currency.NewEuro(900))
+	if !r.Balanced() || len(r.Uncleared) != 0 {
+		t.Errorf("Expected the split lines to be cleared with their parent, got\n%s", r.String())
+	}
+}
+
+func TestLockedPeriodsCannotBeEdited(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.csv")
+	content, err := os.ReadFile("../testdata/reconcile.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	transactions := readTransactions(t, path)
+	unbalanced := reconcile.New(checking, basic(transactions), statementDate, currency.NewEuro(3054.50))
+	if err := reconcile.Lock(path, unbalanced); err == nil {
+		t.Error("Expected an error locking an unbalanced reconciliation")
+	}
+	if err := reconcile.Clear(transactions, "Checking", statementDate, []string{"tx-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.NewBudgetReport(path, transactions, false).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcile.Lock(path, reconcile.New(checking, basic(transactions), statementDate, currency.NewEuro(3054.50))); err != nil {
+		t.Fatal(err)
+	}
+	locks, err := reconcile.ReadLocks(path)
+	if err != nil || len(locks) != 1 || locks[0].Balance.Cmp(currency.NewEuro(3054.50)) != 0 {
+		t.Fatalf("Expected the reconciled period to be recorded, got %v, %v", locks, err)
+	}
+	// Transactions after the statement date, or of other accounts, may still be edited
+	transactions[4].Category = "Food"
+	transactions[5].Category = "Interest"
+	if err := reconcile.CheckSave(path, transactions); err != nil {
+		t.Errorf("Expected edits outside the reconciled period to be allowed, got %v", err)
+	}
+	transactions[1].Amount = currency.NewEuro(-950)
+	if err := reconcile.CheckSave(path, transactions); err == nil || !strings.Contains(err.Error(), "reconciled period") {
+		t.Errorf("Expected an error editing a reconciled transaction, got %v", err)
+	}
+	// Other reports in the same directory have their own locks
+	if other, err := reconcile.ReadLocks(filepath.Join(dir, "other.csv")); err != nil || len(other) != 0 {
+		t.Errorf("Expected no locks for another report, got %v, %v", other, err)
+	}
+	if actual := reconcile.LocksPath(path); actual != filepath.Join(dir, "report.reconciled.csv") {
+		t.Errorf("Expected the locks file to be named after the report, got %s", actual)
+	}
+}
@@ -0,0 +1,184 @@
+package recurring
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Frequency is the unit in which a schedule repeats
+type Frequency string
+
+const (
+	Daily   Frequency = "day"
+	Weekly  Frequency = "week"
+	Monthly Frequency = "month"
+	Yearly  Frequency = "year"
+)
+
+// Schedule repeats every Interval units of Frequency. Monthly and yearly schedules fall on Day of the month, or on
+// the day of the start date if Day is zero, moving to the last day of shorter months
+type Schedule struct {
+	Frequency Frequency
+	Interval  int
+	Day       int
+}
+
+var scheduleRegexp = regexp.MustCompile(`^(?:every\s+(\d+)\s+(day|week|month|year)s?|(daily|weekly|monthly|yearly))(?:\s+on\s+the\s+(\d+)(?:st|nd|rd|th)?)?$`)
+
+var adverbFrequencies = map[string]Frequency{"daily": Daily, "weekly": Weekly, "monthly": Monthly, "yearly": Yearly}
+
+// ParseSchedule parses schedules such as "monthly", "monthly on the 1st", "every 2 weeks" and "yearly"
+func ParseSchedule(value string) (Schedule, error) {
+	match := scheduleRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if match == nil {
+		return Schedule{}, fmt.Errorf("unrecognised schedule %q, expected e.g. \"monthly on the 1st\" or \"every 2 weeks\"", value)
+	}
+	schedule := Schedule{Frequency: adverbFrequencies[match[3]], Interval: 1}
+	if match[1] != "" {
+		schedule.Frequency = Frequency(match[2])
+		schedule.Interval, _ = strconv.Atoi(match[1])
+		if schedule.Interval < 1 {
+			return Schedule{}, fmt.Errorf("schedule %q must repeat at least every 1 %s", value, schedule.Frequency)
+		}
+	}
+	if match[4] != "" {
+		if schedule.Frequency != Monthly && schedule.Frequency != Yearly {
+			return Schedule{}, fmt.Errorf("schedule %q can only fall on a day of the month if it is monthly or yearly", value)
+		}
+		schedule.Day, _ = strconv.Atoi(match[4])
+		if schedule.Day < 1 || schedule.Day > 31 {
+			return Schedule{}, fmt.Errorf("schedule %q has an invalid day of the month", value)
+		}
+	}
+	return schedule, nil
+}
+
+// String formats the schedule in the form understood by ParseSchedule
+func (s Schedule) String() string {
+	str := fmt.Sprintf("every %d %ss", s.Interval, s.Frequency)
+	if s.Interval == 1 {
+		str = map[Frequency]string{Daily: "daily", Weekly: "weekly", Monthly: "monthly", Yearly: "yearly"}[s.Frequency]
+	}
+	if s.Day > 0 {
+		str += fmt.Sprintf(" on the %d", s.Day)
+	}
+	return str
+}
+
+// Definition describes a transaction which repeats on a schedule, such as rent, a salary or a subscription
+type Definition struct {
+	Description string
+	Amount      currency.Euro
+	Category    string
+	PaidBy      string
+	Account     string
+	Schedule    Schedule
+	Start       time.Time
+	// End is the last day the transaction can occur on, or the zero time if it repeats forever
+	End time.Time
+}
+
+// ReadDefinitionsFromFile reads recurring definitions from a CSV file with the columns
+// Description,Amount,Schedule,Start and optionally Category,PaidBy,Account,End
+func ReadDefinitionsFromFile(path string) ([]Definition, error) {
+	var definitions []Definition
+	err := csvfile.Read(path, []string{"Description", "Amount", "Schedule", "Start"}, func(row csvfile.Row) error {
+		definition := Definition{Description: row.Get("Description"), Category: row.Get("Category"), PaidBy: row.Get("PaidBy"), Account: row.Get("Account")}
+		var err error
+		if definition.Amount, err = row.Amount("Amount"); err != nil {
+			return err
+		}
+		if definition.Schedule, err = ParseSchedule(row.Get("Schedule")); err != nil {
+			return err
+		}
+		if definition.Start, err = row.Date("Start"); err != nil {
+			return err
+		}
+		if definition.Start.IsZero() {
+			return fmt.Errorf("missing start date")
+		}
+		if definition.End, err = row.Date("End"); err != nil {
+			return err
+		}
+		definitions = append(definitions, definition)
+		return nil
+	})
+	return definitions, err
+}
+
+// Occurrences returns the dates from from to to, inclusive, on which the transaction occurs
+func (d Definition) Occurrences(from, to time.Time) []time.Time {
+	var dates []time.Time
+	for n := 0; ; n++ {
+		date := d.occurrence(n)
+		if date.After(to) || (!d.End.IsZero() && date.After(d.End)) {
+			return dates
+		}
+		if !date.Before(from) && !date.Before(d.Start) {
+			dates = append(dates, date)
+		}
+	}
+}
+
+// occurrence returns the date of the nth repetition, counting from the start date
+func (d Definition) occurrence(n int) time.Time {
+	steps := n * max(d.Schedule.Interval, 1)
+	switch d.Schedule.Frequency {
+	case Daily:
+		return d.Start.AddDate(0, 0, steps)
+	case Weekly:
+		return d.Start.AddDate(0, 0, 7*steps)
+	case Yearly:
+		steps *= 12
+	}
+	day := d.Schedule.Day
+	if day == 0 {
+		day = d.Start.Day()
+	}
+	month := time.Date(d.Start.Year(), d.Start.Month()+time.Month(steps), 1, 0, 0, 0, 0, d.Start.Location())
+	lastDay := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// Expand creates the transactions of every definition from from to to, inclusive, in date order. The transactions
+// are marked as generated
+func Expand(definitions []Definition, from, to time.Time) []transaction.PayerTransaction {
+	var transactions []transaction.PayerTransaction
+	for _, definition := range definitions {
+		for _, date := range definition.Occurrences(from, to) {
+			transactions = append(transactions, transaction.PayerTransaction{
+				BasicTransaction: transaction.BasicTransaction{Time: date.Format(time.DateOnly), Amount: definition.Amount, Description: definition.Description, Category: definition.Category, Account: definition.Account, Generated: true},
+				PaidBy:           definition.PaidBy,
+			})
+		}
+	}
+	slices.SortStableFunc(transactions, func(a, b transaction.PayerTransaction) int {
+		return strings.Compare(a.Time, b.Time)
+	})
+	return transactions
+}
+
+// AddToReport adds the transactions of every definition from from to to, inclusive, to the report
+func AddToReport(r report.Report, definitions []Definition, from, to time.Time) report.Report {
+	generated := Expand(definitions, from, to)
+	switch r := r.(type) {
+	case report.BasicReport:
+		transactions := r.Transactions()
+		for _, tx := range generated {
+			transactions = append(transactions, tx.BasicTransaction)
+		}
+		return report.NewBasicBudgetReport(r.Name, transactions)
+	case report.MultiPayerReport:
+		return report.NewMultiPayerBudgetReport(r.Name, append(r.Transactions(), generated...))
+	}
+	return r
+}
@@ -0,0 +1,99 @@
+package recurring_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/recurring"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, value)
+	return parsed
+}
+
+func formatDates(dates []time.Time) []string {
+	var formatted []string
+	for _, d := range dates {
+		formatted = append(formatted, d.Format(time.DateOnly))
+	}
+	return formatted
+}
+
+func TestParseSchedule(t *testing.T) {
+	valid := map[string]recurring.Schedule{
+		"monthly":                    {Frequency: recurring.Monthly, Interval: 1},
+		"Monthly on the 1st":         {Frequency: recurring.Monthly, Interval: 1, Day: 1},
+		"every 2 weeks":              {Frequency: recurring.Weekly, Interval: 2},
+		"every 3 months on the 15th": {Frequency: recurring.Monthly, Interval: 3, Day: 15},
+		"yearly":                     {Frequency: recurring.Yearly, Interval: 1},
+	}
+	for value, expected := range valid {
+		actual, err := recurring.ParseSchedule(value)
+		if err != nil {
+			t.Errorf("Got error parsing %q: %v", value, err)
+		}
+		if actual != expected {
+			t.Errorf("Expected %+v for %q, got %+v", expected, value, actual)
+		}
+		if roundTrip, err := recurring.ParseSchedule(actual.String()); err != nil || roundTrip != actual {
+			t.Errorf("Expected %q to parse back to %+v, got %+v (%v)", actual.String(), actual, roundTrip, err)
+		}
+	}
+	for _, value := range []string{"", "fortnightly", "every 0 days", "weekly on the 3rd", "monthly on the 32nd"} {
+		if _, err := recurring.ParseSchedule(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	definitions, err := recurring.ReadDefinitionsFromFile("../testdata/recurring.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		// month ends move to the last day of shorter months
+		"Rent":   {"2025-01-31", "2025-02-28", "2025-03-31"},
+		"Salary": {"2025-01-25", "2025-02-25", "2025-03-25"},
+		// stops after the end date
+		"Gym": {"2025-01-06", "2025-01-20", "2025-02-03", "2025-02-17"},
+		// started before the period
+		"Insurance": {"2025-03-15"},
+	}
+	for _, definition := range definitions {
+		actual := formatDates(definition.Occurrences(date("2025-01-01"), date("2025-03-31")))
+		if !slices.Equal(actual, expected[definition.Description]) {
+			t.Errorf("Expected %s on %v, got %v", definition.Description, expected[definition.Description], actual)
+		}
+	}
+}
+
+func TestAddToReport(t *testing.T) {
+	definitions, err := recurring.ReadDefinitionsFromFile("../testdata/recurring.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.NewBasicBudgetReport("January", []transaction.BasicTransaction{{Time: "2025-01-10", Amount: currency.NewEuro(-50), Description: "Groceries"}})
+	r = recurring.AddToReport(r, definitions, date("2025-01-01"), date("2025-01-31")).(report.BasicReport)
+	transactions := r.Transactions()
+	if len(transactions) != 5 {
+		t.Fatalf("Expected 5 transactions, got %d", len(transactions))
+	}
+	generated := slices.DeleteFunc(slices.Clone(transactions), func(tx transaction.BasicTransaction) bool { return !tx.Generated })
+	if len(generated) != 4 {
+		t.Errorf("Expected 4 generated transactions, got %d", len(generated))
+	}
+	expected := currency.NewEuro(-1010)
+	if actual := r.TotalExpense; actual.Cmp(expected) != 0 {
+		t.Errorf("Expected total expense of %s, got %s", expected.String(), actual.String())
+	}
+	if !strings.Contains(r.String(), "Housing") {
+		t.Errorf("Expected the generated rent to be categorised, got %s", r.String())
+	}
+}
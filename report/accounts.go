@@ -0,0 +1,148 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// totalInvested sums the investment transactions, with money paid into investments as a positive amount
+func totalInvested(transactions []transaction.BasicTransaction) currency.Euro {
+	invested := currency.NewEuro(0.0)
+	for _, tx := range transactions {
+		if tx.Kind == transaction.KindInvestment {
+			invested = currency.SubtractEuros(invested, tx.Amount)
+		}
+	}
+	return invested
+}
+
+// writeTotalInvested writes the amount invested, if any transactions are investments
+func writeTotalInvested(str *strings.Builder, invested currency.Euro) {
+	if invested.Cents() != 0 {
+		str.WriteString(fmt.Sprintf("Total Invested (saved, not spent): %s\n", invested.String()))
+	}
+}
+
+// TransferPair is a transfer out of one account matched with the transfer into another account
+type TransferPair struct {
+	From transaction.PayerTransaction
+	To   transaction.PayerTransaction
+}
+
+// String describes the amount and the accounts of the transfer
+func (p TransferPair) String() string {
+	return fmt.Sprintf("%s,%s from %s to %s", p.From.Time, p.To.Amount.String(), p.From.Account, p.To.Account)
+}
+
+// PairTransfers matches transfers out of one account with the opposite transfers into another account, such as the
+// same transfer appearing in the exports of both a checking and a savings account. Two transactions are paired when
+// they have opposite amounts, different accounts, dates no more than windowDays apart, at least one of them is a
+// transfer and the other is a transfer or has no kind, in which case both are marked as transfers. Investments are
+// never paired. It returns the updated transactions, the pairs, and the transfers which could not be paired
+func PairTransfers(transactions []transaction.PayerTransaction, windowDays int) ([]transaction.PayerTransaction, []TransferPair, []transaction.PayerTransaction) {
+	paired := make([]transaction.PayerTransaction, len(transactions))
+	copy(paired, transactions)
+	matched := make([]bool, len(paired))
+	var pairs []TransferPair
+	for from := range paired {
+		if matched[from] || paired[from].Amount.Cmp(currency.NewEuro(0.0)) >= 0 {
+			continue
+		}
+		best, bestGap := -1, time.Duration(0)
+		for to := range paired {
+			if matched[to] || !isOppositeTransfer(paired[from], paired[to]) {
+				continue
+			}
+			gap, ok := dateGap(paired[from].Time, paired[to].Time)
+			if !ok || gap > time.Duration(windowDays)*24*time.Hour {
+				continue
+			}
+			if best < 0 || gap < bestGap {
+				best, bestGap = to, gap
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		matched[from], matched[best] = true, true
+		paired[from].Kind, paired[best].Kind = transaction.KindTransfer, transaction.KindTransfer
+		pairs = append(pairs, TransferPair{From: paired[from], To: paired[best]})
+	}
+	var unpaired []transaction.PayerTransaction
+	for idx, tx := range paired {
+		if !matched[idx] && tx.Kind == transaction.KindTransfer {
+			unpaired = append(unpaired, tx)
+		}
+	}
+	return paired, pairs, unpaired
+}
+
+// isOppositeTransfer reports whether to could be the other side of the outgoing transfer from
+func isOppositeTransfer(from, to transaction.PayerTransaction) bool {
+	transferOrUnmarked := func(tx transaction.PayerTransaction) bool {
+		return tx.Kind == transaction.KindTransfer || tx.Kind == ""
+	}
+	if !transferOrUnmarked(from) || !transferOrUnmarked(to) || (from.Kind == "" && to.Kind == "") {
+		return false
+	}
+	return from.Account != to.Account && currency.AddEuros(from.Amount, to.Amount).Cents() == 0
+}
+
+// dateGap returns how far apart two transaction times are. Times which are not dates are only comparable when equal
+func dateGap(a, b string) (time.Duration, bool) {
+	dateA, errA := transaction.ParseTime(a)
+	dateB, errB := transaction.ParseTime(b)
+	if errA != nil || errB != nil {
+		return 0, a == b
+	}
+	gap := dateA.Sub(dateB)
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap, true
+}
+
+// PairReportTransfers pairs the transfers in a report as described by PairTransfers, returning the updated report
+func PairReportTransfers(r Report, windowDays int) (Report, []TransferPair, []transaction.PayerTransaction) {
+	switch r := r.(type) {
+	case BasicReport:
+		var transactions []transaction.PayerTransaction
+		for _, tx := range r.Transactions() {
+			transactions = append(transactions, transaction.PayerTransaction{BasicTransaction: tx})
+		}
+		paired, pairs, unpaired := PairTransfers(transactions, windowDays)
+		return NewBasicBudgetReport(r.Name, basicTransactions(paired)), pairs, unpaired
+	case MultiPayerReport:
+		paired, pairs, unpaired := PairTransfers(r.Transactions(), windowDays)
+		return NewMultiPayerBudgetReport(r.Name, paired), pairs, unpaired
+	}
+	return r, nil, nil
+}
+
+// balancePerAccount sums every transaction, including transfers, by account. Transactions without an account are
+// left out
+func balancePerAccount(transactions []transaction.BasicTransaction) map[string]currency.Euro {
+	balances := make(map[string]currency.Euro)
+	for _, tx := range transactions {
+		if tx.Account != "" {
+			balances[tx.Account] = currency.AddEuros(balances[tx.Account], tx.Amount)
+		}
+	}
+	return balances
+}
+
+// writeBalancePerAccount writes the change in balance of each account, if any transactions have an account
+func writeBalancePerAccount(str *strings.Builder, balances map[string]currency.Euro) {
+	if len(balances) == 0 {
+		return
+	}
+	str.WriteString("Balance Change Per Account\n")
+	for _, account := range sortKeys(maps.Keys(balances)) {
+		str.WriteString(fmt.Sprintf("%s: %s\n", account, balances[account].String()))
+	}
+}
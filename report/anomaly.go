@@ -0,0 +1,196 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/text"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// AnomalyKind names the kind of surprise an anomaly highlights
+type AnomalyKind string
+
+const (
+	AnomalyNewPayee         AnomalyKind = "new payee"
+	AnomalyCategorySpike    AnomalyKind = "category above average"
+	AnomalyLargeTransaction AnomalyKind = "large transaction"
+	AnomalyDuplicateCharge  AnomalyKind = "duplicate charge"
+)
+
+// Anomaly is a transaction, or group of transactions, which stands out from the usual spending
+type Anomaly struct {
+	Kind         AnomalyKind                    `json:"kind"`
+	Message      string                         `json:"message"`
+	Transactions []transaction.BasicTransaction `json:"transactions"`
+}
+
+// String describes the kind of anomaly and what stood out
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s: %s", a.Kind, a.Message)
+}
+
+// AnomalyOptions configures which transactions count as anomalies
+type AnomalyOptions struct {
+	// SpikeFactor is how many times its trailing average of up to 3 months a category's expense must exceed, or zero to not
+	// compare categories with their averages
+	SpikeFactor float64
+	// LargeExpense is the size above which a single expense is an anomaly, or zero to not check the size of expenses
+	LargeExpense currency.Euro
+}
+
+// DefaultAnomalyOptions returns the options for detecting anomalies when none are given
+func DefaultAnomalyOptions() AnomalyOptions {
+	return AnomalyOptions{SpikeFactor: 1.5, LargeExpense: currency.NewEuro(500)}
+}
+
+// DetectAnomalies finds the anomalies in the transactions of a period, compared with the transactions of earlier
+// periods in history. Payees which do not appear in the history, and categories whose expense is more than
+// opts.SpikeFactor times their average over up to three months before the period, are only detected when a history is
+// given. Transfers and investments are ignored
+func DetectAnomalies(transactions, history []transaction.BasicTransaction, opts AnomalyOptions) []Anomaly {
+	var anomalies []Anomaly
+	if len(history) > 0 {
+		anomalies = append(anomalies, newPayees(transactions, history)...)
+		if opts.SpikeFactor > 0 {
+			anomalies = append(anomalies, categorySpikes(transactions, history, opts.SpikeFactor)...)
+		}
+	}
+	if opts.LargeExpense.Cents() != 0 {
+		threshold := int64(math.Abs(float64(opts.LargeExpense.Cents())))
+		for _, tx := range transactions {
+			if tx.IsExpense() && -tx.Amount.Cents() > threshold {
+				anomalies = append(anomalies, Anomaly{Kind: AnomalyLargeTransaction, Message: fmt.Sprintf("%s,%s,%s is larger than %s", tx.Time, tx.Amount.String(), tx.Description, currency.NewEuroFromCents(threshold).String()), Transactions: []transaction.BasicTransaction{tx}})
+			}
+		}
+	}
+	return append(anomalies, duplicateCharges(transactions)...)
+}
+
+// payeeName returns who a transaction was paid to or received from, falling back to its description
+func payeeName(tx transaction.BasicTransaction) string {
+	if tx.Payee != "" {
+		return tx.Payee
+	}
+	return tx.Description
+}
+
+// newPayees returns an anomaly for the first transaction with each payee which does not appear in the history
+func newPayees(transactions, history []transaction.BasicTransaction) []Anomaly {
+	known := make(map[string]bool)
+	for _, tx := range history {
+		known[text.Normalize(payeeName(tx))] = true
+	}
+	var anomalies []Anomaly
+	for _, tx := range transactions {
+		name := text.Normalize(payeeName(tx))
+		if !tx.IsIncomeOrExpense() || name == "" || known[name] {
+			continue
+		}
+		known[name] = true
+		anomalies = append(anomalies, Anomaly{Kind: AnomalyNewPayee, Message: fmt.Sprintf("first transaction with %s: %s,%s", payeeName(tx), tx.Time, tx.Amount.String()), Transactions: []transaction.BasicTransaction{tx}})
+	}
+	return anomalies
+}
+
+// categorySpikes returns an anomaly for each category whose expense is more than factor times its monthly average over
+// the three months before the earliest dated transaction, or over fewer months if the history starts later
+func categorySpikes(transactions, history []transaction.BasicTransaction, factor float64) []Anomaly {
+	var start time.Time
+	for _, tx := range transactions {
+		if date, err := tx.Date(); err == nil && (start.IsZero() || date.Before(start)) {
+			start = date
+		}
+	}
+	if start.IsZero() {
+		return nil
+	}
+	end := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	trailingStart := end.AddDate(0, -3, 0)
+	var trailing []transaction.BasicTransaction
+	months := 0
+	for _, tx := range history {
+		if date, err := tx.Date(); err == nil && !date.Before(trailingStart) && date.Before(end) {
+			trailing = append(trailing, tx)
+			months = max(months, (end.Year()-date.Year())*12+int(end.Month()-date.Month()))
+		}
+	}
+	if months == 0 {
+		return nil
+	}
+	totals := totalExpensePer(transactions, ByCategory)
+	trailingTotals := totalExpensePer(trailing, ByCategory)
+	var anomalies []Anomaly
+	for _, category := range sortKeys(maps.Keys(totals)) {
+		average := currency.NewEuroFromCents(int64(math.Round(float64(trailingTotals[category].Cents()) / float64(months))))
+		if average.Cents() == 0 || float64(totals[category].Cents()) >= factor*float64(average.Cents()) {
+			continue
+		}
+		var categoryTransactions []transaction.BasicTransaction
+		for _, tx := range transactions {
+			if tx.IsExpense() && tx.EffectiveCategory() == category {
+				categoryTransactions = append(categoryTransactions, tx)
+			}
+		}
+		message := fmt.Sprintf("%s: %s is %.1fx the trailing %d-month average of %s", category, totals[category].String(), float64(totals[category].Cents())/float64(average.Cents()), months, average.String())
+		anomalies = append(anomalies, Anomaly{Kind: AnomalyCategorySpike, Message: message, Transactions: categoryTransactions})
+	}
+	return anomalies
+}
+
+// duplicateCharges returns an anomaly for each group of expenses with the same time, amount and payee
+func duplicateCharges(transactions []transaction.BasicTransaction) []Anomaly {
+	type chargeKey struct {
+		time  string
+		cents int64
+		payee string
+	}
+	groups := make(map[chargeKey][]transaction.BasicTransaction)
+	var keys []chargeKey
+	for _, tx := range transactions {
+		if !tx.IsExpense() {
+			continue
+		}
+		key := chargeKey{time: tx.Time, cents: tx.Amount.Cents(), payee: text.Normalize(payeeName(tx))}
+		if len(groups[key]) == 0 {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], tx)
+	}
+	var anomalies []Anomaly
+	for _, key := range keys {
+		if group := groups[key]; len(group) > 1 {
+			anomalies = append(anomalies, Anomaly{Kind: AnomalyDuplicateCharge, Message: fmt.Sprintf("%d charges of %s for %s on %s", len(group), group[0].Amount.String(), payeeName(group[0]), key.time), Transactions: group})
+		}
+	}
+	return anomalies
+}
+
+// writeAnomalies writes the anomalies, if there are any
+func writeAnomalies(str *strings.Builder, anomalies []Anomaly) {
+	if len(anomalies) == 0 {
+		return
+	}
+	str.WriteString("Anomalies\n")
+	for _, anomaly := range anomalies {
+		str.WriteString(anomaly.String() + "\n")
+	}
+}
+
+// DetectReportAnomalies detects the anomalies in a report as described by DetectAnomalies, returning the report with
+// its anomalies set
+func DetectReportAnomalies(r Report, history []transaction.BasicTransaction, opts AnomalyOptions) Report {
+	switch r := r.(type) {
+	case BasicReport:
+		r.Anomalies = DetectAnomalies(r.lines(), history, opts)
+		return r
+	case MultiPayerReport:
+		r.Anomalies = DetectAnomalies(r.lines(), history, opts)
+		return r
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readBasicTransactions(t *testing.T, path string) []transaction.BasicTransaction {
+	r, err := report.ReadDefaultBudgetReportFromFile(path, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.Transactions()
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	transactions := readBasicTransactions(t, "../testdata/anomalyreport.csv")
+	history := readBasicTransactions(t, "../testdata/anomalyhistory.csv")
+	anomalies := report.DetectAnomalies(transactions, history, report.DefaultAnomalyOptions())
+	var actual []string
+	for _, anomaly := range anomalies {
+		actual = append(actual, anomaly.String())
+	}
+	expected := []string{
+		"new payee: first transaction with Electronics Store: 2025-04-12,€-800.00",
+		"new payee: first transaction with Netflix: 2025-04-20,€-9.99",
+		"category above average: Groceries: €-650.00 is 2.2x the trailing 3-month average of €-300.00",
+		"large transaction: 2025-04-12,€-800.00,Electronics Store is larger than €500.00",
+		"duplicate charge: 2 charges of €-9.99 for Netflix on 2025-04-20",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected anomalies\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestDetectAnomaliesAveragesOverTheMonthsOfHistory(t *testing.T) {
+	transactions := []transaction.BasicTransaction{{Time: "2025-04-05", Amount: currency.NewEuro(-650), Description: "Supermarket", Category: "Groceries"}}
+	history := []transaction.BasicTransaction{{Time: "2025-03-05", Amount: currency.NewEuro(-300), Description: "Supermarket", Category: "Groceries"}}
+	anomalies := report.DetectAnomalies(transactions, history, report.AnomalyOptions{SpikeFactor: 1.5})
+	expected := "category above average: Groceries: €-650.00 is 2.2x the trailing 1-month average of €-300.00"
+	if len(anomalies) != 1 || anomalies[0].String() != expected {
+		t.Errorf("Expected %s, got %v", expected, anomalies)
+	}
+}
+
+func TestDetectAnomaliesWithoutHistory(t *testing.T) {
+	transactions := readBasicTransactions(t, "../testdata/anomalyreport.csv")
+	anomalies := report.DetectAnomalies(transactions, nil, report.AnomalyOptions{SpikeFactor: 1.5, LargeExpense: currency.NewEuro(0)})
+	if len(anomalies) != 1 || anomalies[0].Kind != report.AnomalyDuplicateCharge {
+		t.Errorf("Expected only the duplicate charge, got %v", anomalies)
+	}
+}
+
+func TestReportAnomaliesSectionAndJSON(t *testing.T) {
+	history := readBasicTransactions(t, "../testdata/anomalyhistory.csv")
+	r := report.DetectReportAnomalies(report.NewBasicBudgetReport("April", readBasicTransactions(t, "../testdata/anomalyreport.csv")), history, report.DefaultAnomalyOptions())
+	if !strings.Contains(r.String(), "Anomalies\nnew payee: first transaction with Electronics Store") {
+		t.Errorf("Expected an anomalies section, got %s", r.String())
+	}
+	buf := new(bytes.Buffer)
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Name         string
+		TotalExpense float64
+		Transactions []map[string]any
+		Anomalies    []report.Anomaly
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Got error decoding %s: %v", buf.String(), err)
+	}
+	if decoded.Name != "April" || decoded.TotalExpense != -1509.98 || len(decoded.Transactions) != 6 || len(decoded.Anomalies) != 5 {
+		t.Errorf("Expected the report's totals, transactions and anomalies, got %s", buf.String())
+	}
+	shared := report.DetectReportAnomalies(report.NewMultiPayerBudgetReport("Shared", []transaction.PayerTransaction{{BasicTransaction: transaction.BasicTransaction{Time: "2025-04-01", Amount: currency.NewEuro(-20), Description: "Taxi"}, PaidBy: "Alice"}}), nil, report.DefaultAnomalyOptions())
+	buf.Reset()
+	if err := shared.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"paidBy": "Alice"`) || !strings.Contains(buf.String(), `"anomalies": []`) {
+		t.Errorf("Expected the payer and no anomalies, got %s", buf.String())
+	}
+}
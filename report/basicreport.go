@@ -1,15 +1,11 @@
 package report
 
 import (
-	"encoding/csv"
-	"errors"
 	"fmt"
 	"io"
-	"maps"
 	"math"
 	"os"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/kevslinger/budget/currency"
@@ -22,81 +18,91 @@ type BasicReport struct {
 	NetIncome    currency.Euro
 	TotalIncome  currency.Euro
 	TotalExpense currency.Euro
+	// TotalInvested is the money paid into investments, which counts as saved rather than spent
+	TotalInvested currency.Euro
+	// Metrics are the ratios computed by ComputeReportMetrics, or nil if it was not run
+	Metrics *Metrics
+	// Anomalies are the surprises found by DetectReportAnomalies, if it was run
+	Anomalies    []Anomaly
 	transactions []transaction.BasicTransaction
 }
 
 // NewBasicBudgetReport creates a new report with a given reportName, calculating the total income, total expense, and net income
+// Transfers between accounts count as neither income nor expense, and investments count towards the total invested
 func NewBasicBudgetReport(reportName string, transactions []transaction.BasicTransaction) BasicReport {
 	totalIncome := currency.NewEuro(0.0)
 	totalExpense := currency.NewEuro(0.0)
-	for _, transaction := range transactions {
-		if transaction.Amount.Cmp(currency.NewEuro(0.0)) < 0 {
+	lines := SplitLines(transactions)
+	for _, transaction := range lines {
+		if transaction.IsExpense() {
 			totalExpense = currency.AddEuros(totalExpense, transaction.Amount)
-		} else {
+		} else if transaction.IsIncome() {
 			totalIncome = currency.AddEuros(totalIncome, transaction.Amount)
 		}
 	}
-	return BasicReport{Name: reportName, NetIncome: currency.AddEuros(totalIncome, totalExpense), TotalIncome: totalIncome, TotalExpense: totalExpense, transactions: transactions}
+	return BasicReport{Name: reportName, NetIncome: currency.AddEuros(totalIncome, totalExpense), TotalIncome: totalIncome, TotalExpense: totalExpense, TotalInvested: totalInvested(lines), transactions: transactions}
 }
 
 // ReadDefaultBudgetReportFromFile reads in a CSV file with transactions, and parses them to create a report
 func ReadDefaultBudgetReportFromFile(reportName string, path string) (BasicReport, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return BasicReport{}, fmt.Errorf("error opening budget report file: %w", err)
-	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	line, err := reader.Read()
+	transactions, _, err := ReadTransactionsFromFile(path)
 	if err != nil {
-		return BasicReport{}, fmt.Errorf("error reading budet report file: %w", err)
-	}
-	var transactions []transaction.BasicTransaction
-	for err == nil {
-		time, amount, description := line[0], line[1], line[2]
-		// skip header
-		if strings.ToLower(time) == "time" && strings.ToLower(amount) == "amount" && strings.ToLower(description) == "description" {
-			line, err = reader.Read()
-			continue
-		}
-		amountFloat, parseErr := strconv.ParseFloat(amount, 64)
-		if parseErr != nil {
-			return BasicReport{}, fmt.Errorf("error parsing a transaction: %w", parseErr)
-		}
-		transactions = append(transactions, transaction.BasicTransaction{Time: time, Amount: currency.NewEuro(amountFloat), Description: description})
-		line, err = reader.Read()
-	}
-	if !errors.Is(err, io.EOF) {
-		return BasicReport{}, fmt.Errorf("error reading currency report file: %w", err)
+		return BasicReport{}, err
 	}
-
-	return NewBasicBudgetReport(reportName, transactions), nil
+	return NewBasicBudgetReport(reportName, basicTransactions(transactions)), nil
 }
 
+// CombineBasicReports merges basic reports into a single report, keeping every transaction
 func CombineBasicReports(reportName string, reports []Report) (BasicReport, error) {
-	transactions := make([]transaction.BasicTransaction, 0)
+	combined, _, err := CombineBasicReportsWithOptions(reportName, reports, DedupOptions{KeepDuplicates: true})
+	return combined, err
+}
+
+// CombineBasicReportsWithOptions merges basic reports into a single report, dropping transactions which appear in more
+// than one report according to opts. It returns the duplicates which were dropped
+func CombineBasicReportsWithOptions(reportName string, reports []Report, opts DedupOptions) (BasicReport, []Duplicate, error) {
+	sources := make([][]transaction.PayerTransaction, 0, len(reports))
 	for _, r := range reports {
 		basicReport, ok := r.(BasicReport)
 		if !ok {
-			return BasicReport{}, fmt.Errorf("expected  BasicReport, got %T", r)
+			return BasicReport{}, nil, fmt.Errorf("expected  BasicReport, got %T", r)
+		}
+		var transactions []transaction.PayerTransaction
+		for _, tx := range basicReport.Transactions() {
+			transactions = append(transactions, transaction.PayerTransaction{BasicTransaction: tx})
 		}
-		transactions = append(transactions, basicReport.Transactions()...)
+		sources = append(sources, transactions)
 	}
-	return NewBasicBudgetReport(reportName, transactions), nil
+	transactions, duplicates := dedupTransactions(sources, opts)
+	return NewBasicBudgetReport(reportName, basicTransactions(transactions)), duplicates, nil
 }
 
 // CalculateTotalExpensePerDescription aggregates all expenses by category, and returns this data as a map
 func (r BasicReport) CalculateTotalExpensePerDescription() map[string]currency.Euro {
-	expensePerDescription := make(map[string]currency.Euro)
-	for _, transaction := range r.transactions {
-		if transaction.Amount.Cmp(currency.NewEuro(0.0)) < 0 {
-			if _, ok := expensePerDescription[transaction.Description]; !ok {
-				expensePerDescription[transaction.Description] = currency.NewEuro(0.0)
-			}
-			expensePerDescription[transaction.Description] = currency.AddEuros(expensePerDescription[transaction.Description], transaction.Amount)
-		}
-	}
-	return expensePerDescription
+	return r.CalculateTotalExpensePerDescriptionAtDepth(0)
+}
+
+// CalculateTotalExpensePerDescriptionAtDepth aggregates all expenses by category, rolling hierarchical categories
+// such as "Food:Groceries" up to their first depth levels. A depth of zero or less keeps the full categories
+func (r BasicReport) CalculateTotalExpensePerDescriptionAtDepth(depth int) map[string]currency.Euro {
+	return totalExpensePerCategory(r.lines(), depth)
+}
+
+// CalculateTotalExpensePer aggregates all expenses by a dimension, such as their payee, and returns this data as a map
+func (r BasicReport) CalculateTotalExpensePer(dimension Dimension) map[string]currency.Euro {
+	return totalExpensePer(r.lines(), dimension)
+}
+
+// CalculateTotalExpensePerTag aggregates all tagged expenses by tag, and returns this data as a map
+// Expenses with several tags count towards each of them
+func (r BasicReport) CalculateTotalExpensePerTag() map[string]currency.Euro {
+	return totalExpensePerTag(r.lines())
+}
+
+// CalculateBalancePerAccount sums the transactions of each account, including transfers, giving the net change in
+// each account's balance over the report's period
+func (r BasicReport) CalculateBalancePerAccount() map[string]currency.Euro {
+	return balancePerAccount(r.lines())
 }
 
 // Save saves the report's transactions to a CSV file
@@ -118,16 +124,22 @@ func (r BasicReport) Save(filename string) error {
 
 // WriteCSV writes the report to a CSV
 func (r BasicReport) WriteCSV(writer io.Writer) error {
-	fmt.Fprint(writer, "Time,Amount,Description")
+	columns := usedColumns(r.transactions)
+	fmt.Fprint(writer, "Time,Amount,Description"+columnHeader(columns))
 	for _, income := range r.SortIncomes() {
-		fmt.Fprintf(writer, "\n%s,%.2f,%s", income.Time, float64(income.Amount.Cents())/100, income.Description)
+		fmt.Fprintf(writer, "\n%s,%.2f,%s%s", income.Time, float64(income.Amount.Cents())/100, csvField(income.Description), columnValues(columns, income))
 	}
 	for _, expense := range r.SortExpenses() {
-		fmt.Fprintf(writer, "\n%s,%.2f,%s", expense.Time, float64(expense.Amount.Cents())/100, expense.Description)
+		fmt.Fprintf(writer, "\n%s,%.2f,%s%s", expense.Time, float64(expense.Amount.Cents())/100, csvField(expense.Description), columnValues(columns, expense))
 	}
 	return nil
 }
 
+// WriteJSON writes the report's totals, transactions and anomalies as JSON
+func (r BasicReport) WriteJSON(writer io.Writer) error {
+	return writeJSON(writer, jsonReport[transaction.BasicTransaction]{Name: r.Name, TotalIncome: r.TotalIncome, TotalExpense: r.TotalExpense, NetIncome: r.NetIncome, TotalInvested: r.TotalInvested, Metrics: r.Metrics, Transactions: r.transactions, Anomalies: r.Anomalies})
+}
+
 // SortIncomes sort the incomes in the report from largest to smallest
 func (r BasicReport) SortIncomes() []transaction.BasicTransaction {
 	var incomes []transaction.BasicTransaction
@@ -166,27 +178,34 @@ func (r BasicReport) String() string {
 	var str strings.Builder
 	str.WriteString(fmt.Sprintf("currency Report for the period %s\n", r.Name))
 	str.WriteString(fmt.Sprintf("Total Income: %s, Total Expense: %s, Net Income: %s\n", r.TotalIncome.String(), r.TotalExpense.String(), r.NetIncome.String()))
+	writeTotalInvested(&str, r.TotalInvested)
 	str.WriteString("Total Expense Per Category (% of total expenses)\n")
-	expensePerDescription := r.CalculateTotalExpensePerDescription()
-	sortedKeys := sortKeys(maps.Keys(expensePerDescription))
-	for _, key := range sortedKeys {
-		str.WriteString(fmt.Sprintf("%s: %s (%.2f%%)\n", key, expensePerDescription[key].String(), 100*float64(expensePerDescription[key].Cents())/float64(r.TotalExpense.Cents())))
-	}
-	str.WriteString("Time,Amount,Description\n")
+	writeExpenseTree(&str, r.CalculateTotalExpensePerDescription(), r.TotalExpense)
+	writeExpensePerTag(&str, r.CalculateTotalExpensePerTag(), r.TotalExpense)
+	writeBalancePerAccount(&str, r.CalculateBalancePerAccount())
+	writeMetrics(&str, r.Metrics)
+	writeAnomalies(&str, r.Anomalies)
+	columns := usedColumns(r.transactions)
+	str.WriteString("Time,Amount,Description" + columnHeader(columns) + "\n")
 	for _, income := range r.SortIncomes() {
-		str.WriteString(fmt.Sprintf("%s,%s,%s\n", income.Time, income.Amount.String(), income.Description))
+		str.WriteString(fmt.Sprintf("%s,%s,%s%s\n", income.Time, income.Amount.String(), income.Description, columnValues(columns, income)))
 	}
 	for _, expense := range r.SortExpenses() {
-		str.WriteString(fmt.Sprintf("%s,%s,%s\n", expense.Time, expense.Amount.String(), expense.Description))
+		str.WriteString(fmt.Sprintf("%s,%s,%s%s\n", expense.Time, expense.Amount.String(), expense.Description, columnValues(columns, expense)))
 	}
 	return str.String()
 }
 
+// lines returns the report's transactions with every split transaction replaced by its child lines
+func (r BasicReport) lines() []transaction.BasicTransaction {
+	return SplitLines(r.transactions)
+}
+
 // Transactions returns a copy of the transactions from the report
 func (r BasicReport) Transactions() []transaction.BasicTransaction {
 	var transactions []transaction.BasicTransaction
 	for _, tx := range r.transactions {
-		transactions = append(transactions, transaction.BasicTransaction{Time: tx.Time, Amount: tx.Amount, Description: tx.Description})
+		transactions = append(transactions, tx)
 	}
 	return transactions
 }
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// CategorySeparator separates the levels of a hierarchical category, such as "Food:Groceries"
+const CategorySeparator = ":"
+
+// CategoryAtDepth rolls a hierarchical category up to its first depth levels, so "Food:Groceries:Bakery" at depth 1
+// is "Food". A depth of zero or less keeps the full category
+func CategoryAtDepth(category string, depth int) string {
+	if depth <= 0 {
+		return category
+	}
+	levels := strings.Split(category, CategorySeparator)
+	if len(levels) <= depth {
+		return category
+	}
+	return strings.Join(levels[:depth], CategorySeparator)
+}
+
+// Dimension is a field of a transaction which expenses can be aggregated by
+type Dimension int
+
+const (
+	// ByCategory aggregates by category, falling back to the description of uncategorised transactions
+	ByCategory Dimension = iota
+	// ByPayee aggregates by payee, falling back to the description of transactions without a payee
+	ByPayee
+	// ByDescription aggregates by the free-text description
+	ByDescription
+)
+
+// key returns the value of the dimension for a transaction
+func (d Dimension) key(tx transaction.BasicTransaction) string {
+	switch d {
+	case ByPayee:
+		if tx.Payee != "" {
+			return tx.Payee
+		}
+		return tx.Description
+	case ByDescription:
+		return tx.Description
+	}
+	return tx.EffectiveCategory()
+}
+
+// totalExpensePer aggregates the expenses by a dimension
+func totalExpensePer(transactions []transaction.BasicTransaction, dimension Dimension) map[string]currency.Euro {
+	expensePer := make(map[string]currency.Euro)
+	for _, transaction := range transactions {
+		if transaction.IsExpense() {
+			key := dimension.key(transaction)
+			expensePer[key] = currency.AddEuros(expensePer[key], transaction.Amount)
+		}
+	}
+	return expensePer
+}
+
+// totalExpensePerCategory aggregates the expenses by category, rolled up to the given depth
+func totalExpensePerCategory(transactions []transaction.BasicTransaction, depth int) map[string]currency.Euro {
+	expensePerCategory := make(map[string]currency.Euro)
+	for category, amount := range totalExpensePer(transactions, ByCategory) {
+		category = CategoryAtDepth(category, depth)
+		expensePerCategory[category] = currency.AddEuros(expensePerCategory[category], amount)
+	}
+	return expensePerCategory
+}
+
+// writeExpenseTree writes the expenses per category as a tree, with each sub-category indented below its parent
+// Top-level categories show their share of the total expense, and sub-categories their share of both their
+// parent and the total expense
+func writeExpenseTree(str *strings.Builder, expensePerCategory map[string]currency.Euro, totalExpense currency.Euro) {
+	// every level of every category, e.g. "Food" and "Food:Groceries" for "Food:Groceries"
+	nodes := make(map[string]currency.Euro)
+	for category, amount := range expensePerCategory {
+		levels := strings.Split(category, CategorySeparator)
+		for depth := 1; depth <= len(levels); depth++ {
+			node := strings.Join(levels[:depth], CategorySeparator)
+			nodes[node] = currency.AddEuros(nodes[node], amount)
+		}
+	}
+	// sort level by level, so that sub-categories directly follow their parent
+	sortedNodes := slices.SortedFunc(maps.Keys(nodes), func(a, b string) int {
+		return slices.Compare(strings.Split(a, CategorySeparator), strings.Split(b, CategorySeparator))
+	})
+	for _, node := range sortedNodes {
+		depth := strings.Count(node, CategorySeparator)
+		name := node[strings.LastIndex(node, CategorySeparator)+1:]
+		if depth == 0 {
+			str.WriteString(fmt.Sprintf("%s: %s (%.2f%%)\n", name, nodes[node].String(), percentage(nodes[node], totalExpense)))
+			continue
+		}
+		parent := node[:strings.LastIndex(node, CategorySeparator)]
+		str.WriteString(fmt.Sprintf("%s%s: %s (%.2f%% of %s, %.2f%% of total)\n", strings.Repeat("  ", depth), name, nodes[node].String(), percentage(nodes[node], nodes[parent]), parent, percentage(nodes[node], totalExpense)))
+	}
+}
+
+// percentage returns part as a percentage of whole, or zero if whole is zero
+func percentage(part, whole currency.Euro) float64 {
+	if whole.Cents() == 0 {
+		return 0
+	}
+	return 100 * float64(part.Cents()) / float64(whole.Cents())
+}
@@ -0,0 +1,414 @@
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// payerColumnNames are the header names which identify the column saying who earned/paid a transaction
+var payerColumnNames = []string{"paid by", "name", "payer"}
+
+// column is an optional CSV column. Optional columns are read when present in the header, and written only when
+// at least one transaction in the report has a value for them
+type column struct {
+	// names are the accepted header names, the first of which is used when writing
+	names []string
+	get   func(tx transaction.BasicTransaction) string
+	set   func(tx *transaction.BasicTransaction, value string) error
+}
+
+var optionalColumns = []column{
+	{
+		names: []string{"ID", "FITID"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.ID },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.ID = value
+			return nil
+		},
+	},
+	{
+		names: []string{"Category"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.Category },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Category = value
+			return nil
+		},
+	},
+	{
+		names: []string{"Payee", "Merchant"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.Payee },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Payee = value
+			return nil
+		},
+	},
+	{
+		names: []string{"Notes"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.Notes },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Notes = value
+			return nil
+		},
+	},
+	{
+		names: []string{"Tags"},
+		get:   func(tx transaction.BasicTransaction) string { return strings.Join(tx.Tags, TagSeparator) },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Tags = ParseTags(value)
+			return nil
+		},
+	},
+	{
+		names: []string{"Account"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.Account },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Account = value
+			return nil
+		},
+	},
+	{
+		names: []string{"Kind"},
+		get:   func(tx transaction.BasicTransaction) string { return string(tx.Kind) },
+		set: func(tx *transaction.BasicTransaction, value string) (err error) {
+			tx.Kind, err = transaction.ParseKind(value)
+			return err
+		},
+	},
+	{
+		names: []string{"Generated"},
+		get: func(tx transaction.BasicTransaction) string {
+			if tx.Generated {
+				return "yes"
+			}
+			return ""
+		},
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			generated, err := csvfile.ParseBool(value)
+			tx.Generated = generated
+			return err
+		},
+	},
+	{
+		names: []string{"VAT Rate", "VATRate"},
+		get: func(tx transaction.BasicTransaction) string {
+			if tx.VATRate == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*tx.VATRate, 'f', -1, 64)
+		},
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			if value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")); value == "" {
+				return nil
+			}
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			tx.VATRate = &rate
+			return nil
+		},
+	},
+	{
+		names: []string{"VAT"},
+		get: func(tx transaction.BasicTransaction) string {
+			if tx.VAT == nil {
+				return ""
+			}
+			return fmt.Sprintf("%.2f", float64(tx.VAT.Cents())/100)
+		},
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			if value = strings.TrimSpace(value); value == "" {
+				return nil
+			}
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			vat := currency.NewEuro(amount)
+			tx.VAT = &vat
+			return nil
+		},
+	},
+	{
+		names: []string{"Attachments"},
+		get:   func(tx transaction.BasicTransaction) string { return strings.Join(tx.Attachments, TagSeparator) },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Attachments = ParseTags(value)
+			return nil
+		},
+	},
+	{
+		names: []string{"Parent"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.Parent },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.Parent = strings.TrimSpace(value)
+			return nil
+		},
+	},
+	{
+		names: []string{"Refund Of", "RefundOf"},
+		get:   func(tx transaction.BasicTransaction) string { return tx.RefundOf },
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			tx.RefundOf = strings.TrimSpace(value)
+			return nil
+		},
+	},
+	{
+		names: []string{"Reimbursable"},
+		get: func(tx transaction.BasicTransaction) string {
+			if tx.Reimbursable {
+				return "yes"
+			}
+			return ""
+		},
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			reimbursable, err := csvfile.ParseBool(value)
+			tx.Reimbursable = reimbursable
+			return err
+		},
+	},
+	{
+		names: []string{"Cleared"},
+		get: func(tx transaction.BasicTransaction) string {
+			if tx.Cleared {
+				return "yes"
+			}
+			return ""
+		},
+		set: func(tx *transaction.BasicTransaction, value string) error {
+			cleared, err := csvfile.ParseBool(value)
+			tx.Cleared = cleared
+			return err
+		},
+	},
+}
+
+// TagSeparator separates the tags of a transaction in the Tags column
+const TagSeparator = ";"
+
+// ParseTags splits a Tags column into its tags, ignoring blank tags
+func ParseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, TagSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// csvLayout records where each column can be found in the rows of a CSV report file
+type csvLayout struct {
+	time, amount, description, payer int
+	optional                         map[int]column
+}
+
+// newCSVLayout determines the layout of a report file from its first row, reporting whether that row is a header.
+// Files without a header are expected to contain Time,Amount,Description[,Paid By]
+func newCSVLayout(line []string) (csvLayout, bool) {
+	layout := csvLayout{time: -1, amount: -1, description: -1, payer: -1, optional: make(map[int]column)}
+	for idx, name := range line {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "time":
+			layout.time = idx
+		case name == "amount":
+			layout.amount = idx
+		case name == "description":
+			layout.description = idx
+		case slices.Contains(payerColumnNames, name):
+			layout.payer = idx
+		default:
+			for _, c := range optionalColumns {
+				if slices.ContainsFunc(c.names, func(n string) bool { return strings.ToLower(n) == name }) {
+					layout.optional[idx] = c
+				}
+			}
+		}
+	}
+	if layout.time >= 0 && layout.amount >= 0 {
+		return layout, true
+	}
+	layout = csvLayout{time: 0, amount: 1, description: 2, payer: -1, optional: make(map[int]column)}
+	if len(line) > 3 {
+		layout.payer = 3
+	}
+	return layout, false
+}
+
+// index returns the index of the optional column with the given name, or -1 if the file does not have it
+func (l csvLayout) index(name string) int {
+	for idx, c := range l.optional {
+		if c.names[0] == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// field returns the value at idx in line, or the empty string if the column is absent
+func field(line []string, idx int) string {
+	if idx < 0 || idx >= len(line) {
+		return ""
+	}
+	return line[idx]
+}
+
+// parse converts a row of a report file into a transaction, returning the problems which make the row unusable
+func (l csvLayout) parse(line []string) (transaction.PayerTransaction, []Problem) {
+	var problems []Problem
+	amount := strings.TrimSpace(field(line, l.amount))
+	amountFloat, err := strconv.ParseFloat(amount, 64)
+	if amount == "" {
+		problems = append(problems, Problem{Column: l.amount + 1, Name: "Amount", Reason: "missing amount", Fatal: true})
+	} else if err != nil {
+		problems = append(problems, Problem{Column: l.amount + 1, Name: "Amount", Value: amount, Reason: "not a number", Fatal: true})
+	}
+	tx := transaction.PayerTransaction{
+		BasicTransaction: transaction.BasicTransaction{Time: field(line, l.time), Amount: currency.NewEuro(amountFloat), Description: field(line, l.description)},
+		PaidBy:           field(line, l.payer),
+	}
+	for _, idx := range slices.Sorted(maps.Keys(l.optional)) {
+		c := l.optional[idx]
+		if err := c.set(&tx.BasicTransaction, field(line, idx)); err != nil {
+			problems = append(problems, Problem{Column: idx + 1, Name: c.names[0], Value: field(line, idx), Reason: err.Error(), Fatal: true})
+		}
+	}
+	return tx, problems
+}
+
+// check returns the problems with a parsed row which do not make it unusable: a missing or unrecognised time, and a
+// missing description. Child lines of split transactions inherit these from their parent
+func (l csvLayout) check(tx transaction.PayerTransaction) []Problem {
+	if tx.Parent != "" {
+		return nil
+	}
+	var problems []Problem
+	if strings.TrimSpace(tx.Time) == "" {
+		problems = append(problems, Problem{Column: l.time + 1, Name: "Time", Reason: "missing time"})
+	} else if _, err := tx.Date(); err != nil {
+		problems = append(problems, Problem{Column: l.time + 1, Name: "Time", Value: tx.Time, Reason: "unrecognised date"})
+	}
+	if l.description >= 0 && strings.TrimSpace(tx.Description) == "" {
+		problems = append(problems, Problem{Column: l.description + 1, Name: "Description", Reason: "missing description"})
+	}
+	return problems
+}
+
+// ReadTransactionsFromFile reads every transaction in a CSV report file, and reports whether the file has a column
+// saying who earned/paid each transaction. Transactions from files without such a column have an empty PaidBy
+// It returns an error, with its line and column, at the first row which cannot be read. Refunds of a transaction or
+// category which is not in the file are left uncategorised, to be resolved by ResolveRefunds with the other files
+func ReadTransactionsFromFile(path string) ([]transaction.PayerTransaction, bool, error) {
+	rows, layout, err := readRows(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var transactions []transaction.PayerTransaction
+	for _, row := range rows {
+		if idx := slices.IndexFunc(row.problems, func(p Problem) bool { return p.Fatal }); idx >= 0 {
+			return nil, false, fmt.Errorf("error parsing a transaction: %w", row.problems[idx])
+		}
+		transactions = append(transactions, row.tx)
+	}
+	if problems := resolveSplits(transactions); len(problems) > 0 {
+		return nil, false, problems[0]
+	}
+	resolveRefunds(transactions)
+	return transactions, layout.payer >= 0, nil
+}
+
+// row is a row of a report file with the problems found in it
+type row struct {
+	line     int
+	tx       transaction.PayerTransaction
+	problems []Problem
+}
+
+// readRows reads and parses every row of a CSV report file, collecting the problems of each row rather than stopping
+// at the first one. Rows with a different number of columns from the header, or the first row of files without a
+// header, are a problem
+func readRows(path string) ([]row, csvLayout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, csvLayout{}, fmt.Errorf("error opening budget report file: %w", err)
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	line, err := reader.Read()
+	if err != nil {
+		return nil, csvLayout{}, fmt.Errorf("error reading budet report file: %w", err)
+	}
+	columns := len(line)
+	layout, isHeader := newCSVLayout(line)
+	if isHeader {
+		line, err = reader.Read()
+	}
+	var rows []row
+	for err == nil {
+		lineNumber, _ := reader.FieldPos(0)
+		tx, problems := layout.parse(line)
+		if len(line) != columns {
+			problems = append([]Problem{{Reason: fmt.Sprintf("expected %d columns, got %d", columns, len(line)), Fatal: true}}, problems...)
+		}
+		problems = append(problems, layout.check(tx)...)
+		for i := range problems {
+			problems[i].File, problems[i].Line = path, lineNumber
+		}
+		rows = append(rows, row{line: lineNumber, tx: tx, problems: problems})
+		line, err = reader.Read()
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, csvLayout{}, fmt.Errorf("error reading currency report file: %w", err)
+	}
+	return rows, layout, nil
+}
+
+// usedColumns returns the optional columns for which at least one of the transactions has a value
+func usedColumns(transactions []transaction.BasicTransaction) []column {
+	var columns []column
+	for _, c := range optionalColumns {
+		if slices.ContainsFunc(transactions, func(tx transaction.BasicTransaction) bool { return c.get(tx) != "" }) {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// columnHeader returns the header text for the optional columns, including the leading separator
+func columnHeader(columns []column) string {
+	var str strings.Builder
+	for _, c := range columns {
+		str.WriteString("," + c.names[0])
+	}
+	return str.String()
+}
+
+// columnValues returns the transaction's values for the optional columns, including the leading separator
+func columnValues(columns []column, tx transaction.BasicTransaction) string {
+	var str strings.Builder
+	for _, c := range columns {
+		str.WriteString("," + csvField(c.get(tx)))
+	}
+	return str.String()
+}
+
+// csvField quotes a value if it contains characters which would otherwise break the CSV row
+func csvField(value string) string {
+	if !strings.ContainsAny(value, ",\"\n") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
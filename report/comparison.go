@@ -0,0 +1,282 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Delta is how an amount changed from the previous report to the current report
+type Delta struct {
+	Name     string
+	Previous currency.Euro
+	Current  currency.Euro
+	// Expense marks amounts which are spent, and so negative, whose percent change is the growth in the amount spent
+	Expense bool
+}
+
+// Change returns the current amount minus the previous amount
+func (d Delta) Change() currency.Euro {
+	return currency.SubtractEuros(d.Current, d.Previous)
+}
+
+// PercentChange returns the change relative to the size of the previous amount, so a net income which went from
+// €-100 to €100 changed by +200%. For expenses it is the growth in the amount spent, so an expense which grew from
+// €-300 to €-450 changed by +50%. It returns false if the previous amount is zero
+func (d Delta) PercentChange() (float64, bool) {
+	previous, current := d.Previous.Cents(), d.Current.Cents()
+	if previous == 0 {
+		return 0, false
+	}
+	if d.Expense {
+		previous, current = -previous, -current
+	}
+	return 100 * float64(current-previous) / float64(max(previous, -previous)), true
+}
+
+// String describes the previous and current amounts and the change between them
+func (d Delta) String() string {
+	str := fmt.Sprintf("%s: %s -> %s (%s", d.Name, d.Previous.String(), d.Current.String(), d.Change().String())
+	if percent, ok := d.PercentChange(); ok {
+		str += fmt.Sprintf(", %+.2f%%", percent)
+	}
+	return str + ")"
+}
+
+// MetricDelta is how a ratio, in percent, changed from the previous report to the current report
+type MetricDelta struct {
+	Name     string
+	Previous float64
+	Current  float64
+}
+
+// String describes the previous and current ratios and the change in percentage points
+func (d MetricDelta) String() string {
+	return fmt.Sprintf("%s: %.2f%% -> %.2f%% (%+.2f points)", d.Name, d.Previous, d.Current, d.Current-d.Previous)
+}
+
+// ComparisonReport compares the totals, and the expenses per category and per person, of two reports, such as this
+// month and last month, or this month and the same month last year
+type ComparisonReport struct {
+	PreviousName string
+	CurrentName  string
+	TotalIncome  Delta
+	TotalExpense Delta
+	NetIncome    Delta
+	// Categories are the expenses of the categories in either report, sorted by name
+	Categories []Delta
+	// Payers are the expenses of the people in either report, sorted by name. Only shared reports have payers
+	Payers []Delta
+	// Metrics are the ratios of both reports, using their metrics if they have been computed. The essential,
+	// discretionary and fixed-cost ratios are only compared when both reports were classified
+	Metrics           []MetricDelta
+	AverageDailySpend Delta
+}
+
+// NewComparisonReport compares the previous report with the current report
+func NewComparisonReport(previous, current Report) (ComparisonReport, error) {
+	previousTotals, err := comparisonTotals(previous)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	currentTotals, err := comparisonTotals(current)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	return ComparisonReport{
+		PreviousName:      previousTotals.name,
+		CurrentName:       currentTotals.name,
+		TotalIncome:       Delta{Name: "Total Income", Previous: previousTotals.totalIncome, Current: currentTotals.totalIncome},
+		TotalExpense:      Delta{Name: "Total Expense", Previous: previousTotals.totalExpense, Current: currentTotals.totalExpense, Expense: true},
+		NetIncome:         Delta{Name: "Net Income", Previous: previousTotals.netIncome, Current: currentTotals.netIncome},
+		Categories:        deltas(previousTotals.expensePerCategory, currentTotals.expensePerCategory),
+		Payers:            deltas(previousTotals.expensePerPayer, currentTotals.expensePerPayer),
+		Metrics:           metricDeltas(previousTotals.metrics, currentTotals.metrics),
+		AverageDailySpend: Delta{Name: "Average Daily Spend", Previous: previousTotals.metrics.AverageDailySpend, Current: currentTotals.metrics.AverageDailySpend, Expense: true},
+	}, nil
+}
+
+// metricDeltas pairs up the ratios of both metrics
+func metricDeltas(previous, current Metrics) []MetricDelta {
+	deltas := []MetricDelta{{Name: "Savings Rate", Previous: previous.SavingsRate, Current: current.SavingsRate}}
+	if previous.Classified && current.Classified {
+		deltas = append(deltas,
+			MetricDelta{Name: "Essential Share", Previous: previous.EssentialShare, Current: current.EssentialShare},
+			MetricDelta{Name: "Discretionary Share", Previous: previous.DiscretionaryShare, Current: current.DiscretionaryShare},
+			MetricDelta{Name: "Fixed-Cost Ratio", Previous: previous.FixedCostRatio, Current: current.FixedCostRatio})
+	}
+	return deltas
+}
+
+// NewPeriodComparisonReport compares the transactions of two periods, each a year, month or day such as "2025",
+// "2025-03" or "2025-03-14". Transactions are treated as shared if multiPayer is set
+func NewPeriodComparisonReport(transactions []transaction.PayerTransaction, previousPeriod, currentPeriod string, multiPayer bool) (ComparisonReport, error) {
+	previousStart, previousEnd, err := parsePeriod(previousPeriod)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	currentStart, currentEnd, err := parsePeriod(currentPeriod)
+	if err != nil {
+		return ComparisonReport{}, err
+	}
+	var previous, current []transaction.PayerTransaction
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil {
+			continue
+		}
+		if !date.Before(previousStart) && date.Before(previousEnd) {
+			previous = append(previous, tx)
+		}
+		if !date.Before(currentStart) && date.Before(currentEnd) {
+			current = append(current, tx)
+		}
+	}
+	return NewComparisonReport(NewBudgetReport(previousPeriod, previous, multiPayer), NewBudgetReport(currentPeriod, current, multiPayer))
+}
+
+// parsePeriod returns the first day of a year, month or day, such as "2025", "2025-03" or "2025-03-14", and the first
+// day after it
+func parsePeriod(period string) (time.Time, time.Time, error) {
+	if start, err := time.Parse("2006", period); err == nil {
+		return start, start.AddDate(1, 0, 0), nil
+	}
+	if start, err := time.Parse("2006-01", period); err == nil {
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	if start, err := time.Parse("2006-01-02", period); err == nil {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unrecognised period %q, expected a year, month or day such as 2025, 2025-03 or 2025-03-14", period)
+}
+
+// totals are the figures of a report which are compared
+type totals struct {
+	name                                 string
+	totalIncome, totalExpense, netIncome currency.Euro
+	expensePerCategory, expensePerPayer  map[string]currency.Euro
+	metrics                              Metrics
+}
+
+// comparisonTotals returns the figures of a report which are compared
+func comparisonTotals(r Report) (totals, error) {
+	switch r := r.(type) {
+	case BasicReport:
+		return totals{name: r.Name, totalIncome: r.TotalIncome, totalExpense: r.TotalExpense, netIncome: r.NetIncome, expensePerCategory: r.CalculateTotalExpensePerDescription(), metrics: reportMetrics(r.Metrics, r.lines())}, nil
+	case MultiPayerReport:
+		return totals{name: r.Name, totalIncome: r.TotalIncome, totalExpense: r.TotalExpense, netIncome: r.NetIncome, expensePerCategory: r.CalculateTotalExpensePerDescription(), expensePerPayer: r.TotalExpensePerPayer, metrics: reportMetrics(r.Metrics, r.lines())}, nil
+	}
+	return totals{}, fmt.Errorf("unknown report type: %T", r)
+}
+
+// reportMetrics returns the metrics of a report, computing them without options if they have not been computed
+func reportMetrics(metrics *Metrics, transactions []transaction.BasicTransaction) Metrics {
+	if metrics != nil {
+		return *metrics
+	}
+	return CalculateMetrics(transactions, MetricsOptions{})
+}
+
+// deltas pairs up the expenses of every name in either map, sorted by name
+func deltas(previous, current map[string]currency.Euro) []Delta {
+	names := make(map[string]bool)
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	var deltas []Delta
+	for _, name := range sortKeys(maps.Keys(names)) {
+		deltas = append(deltas, Delta{Name: name, Previous: previous[name], Current: current[name], Expense: true})
+	}
+	return deltas
+}
+
+// NewCategories returns the categories with expenses in the current report but not the previous report
+func (r ComparisonReport) NewCategories() []string {
+	var categories []string
+	for _, delta := range r.Categories {
+		if delta.Previous.Cents() == 0 {
+			categories = append(categories, delta.Name)
+		}
+	}
+	return categories
+}
+
+// DisappearedCategories returns the categories with expenses in the previous report but not the current report
+func (r ComparisonReport) DisappearedCategories() []string {
+	var categories []string
+	for _, delta := range r.Categories {
+		if delta.Current.Cents() == 0 {
+			categories = append(categories, delta.Name)
+		}
+	}
+	return categories
+}
+
+// String returns a summary of the changes in the totals, expense per category and expense per person, and lists the
+// new and disappeared categories
+func (r ComparisonReport) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Comparison of %s with %s\n", r.CurrentName, r.PreviousName))
+	str.WriteString(r.TotalIncome.String() + "\n")
+	str.WriteString(r.TotalExpense.String() + "\n")
+	str.WriteString(r.NetIncome.String() + "\n")
+	str.WriteString("Expense Per Category\n")
+	for _, delta := range r.Categories {
+		str.WriteString(delta.String() + "\n")
+	}
+	if categories := r.NewCategories(); len(categories) > 0 {
+		str.WriteString("New Categories: " + strings.Join(categories, ", ") + "\n")
+	}
+	if categories := r.DisappearedCategories(); len(categories) > 0 {
+		str.WriteString("Disappeared Categories: " + strings.Join(categories, ", ") + "\n")
+	}
+	if len(r.Payers) > 0 {
+		str.WriteString("Expense Per Person\n")
+		for _, delta := range r.Payers {
+			str.WriteString(delta.String() + "\n")
+		}
+	}
+	str.WriteString("Metrics\n")
+	for _, delta := range r.Metrics {
+		str.WriteString(delta.String() + "\n")
+	}
+	str.WriteString(r.AverageDailySpend.String() + "\n")
+	return str.String()
+}
+
+// WriteCSV writes every delta as a row with the columns Section,Name,Previous,Current,Change,Percent Change, where the
+// section is Total, Category, Person or Metric. The percent change is that of Delta.PercentChange, so positive for a
+// growing expense, and is left empty when the previous amount is zero. For metrics, which are already percentages, the
+// change is in percentage points
+func (r ComparisonReport) WriteCSV(writer io.Writer) error {
+	fmt.Fprint(writer, "Section,Name,Previous,Current,Change,Percent Change")
+	writeRow := func(section string, delta Delta) {
+		percentChange := ""
+		if percent, ok := delta.PercentChange(); ok {
+			percentChange = fmt.Sprintf("%.2f", percent)
+		}
+		fmt.Fprintf(writer, "\n%s,%s,%.2f,%.2f,%.2f,%s", section, csvField(delta.Name), float64(delta.Previous.Cents())/100, float64(delta.Current.Cents())/100, float64(delta.Change().Cents())/100, percentChange)
+	}
+	for _, delta := range []Delta{r.TotalIncome, r.TotalExpense, r.NetIncome} {
+		writeRow("Total", delta)
+	}
+	for _, delta := range r.Categories {
+		writeRow("Category", delta)
+	}
+	for _, delta := range r.Payers {
+		writeRow("Person", delta)
+	}
+	for _, delta := range r.Metrics {
+		fmt.Fprintf(writer, "\nMetric,%s,%.2f,%.2f,%.2f,", delta.Name, delta.Previous, delta.Current, delta.Current-delta.Previous)
+	}
+	writeRow("Metric", r.AverageDailySpend)
+	return nil
+}
@@ -0,0 +1,106 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func TestComparisonReport(t *testing.T) {
+	previous := report.NewBasicBudgetReport("March", []transaction.BasicTransaction{
+		{Time: "2025-03-01", Amount: currency.NewEuro(2000), Description: "Salary"},
+		{Time: "2025-03-05", Amount: currency.NewEuro(-300), Description: "Supermarket", Category: "Groceries"},
+		{Time: "2025-03-12", Amount: currency.NewEuro(-60), Description: "Cinema", Category: "Fun"},
+	})
+	current := report.NewBasicBudgetReport("April", []transaction.BasicTransaction{
+		{Time: "2025-04-01", Amount: currency.NewEuro(2100), Description: "Salary"},
+		{Time: "2025-04-05", Amount: currency.NewEuro(-450), Description: "Supermarket", Category: "Groceries"},
+		{Time: "2025-04-20", Amount: currency.NewEuro(-9.99), Description: "Netflix", Category: "Streaming"},
+	})
+	comparison, err := report.NewComparisonReport(previous, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Comparison of April with March\n" +
+		"Total Income: €2000.00 -> €2100.00 (€100.00, +5.00%)\n" +
+		"Total Expense: €-360.00 -> €-459.99 (€-99.99, +27.77%)\n" +
+		"Net Income: €1640.00 -> €1640.01 (€0.01, +0.00%)\n" +
+		"Expense Per Category\n" +
+		"Fun: €-60.00 -> €0.00 (€60.00, -100.00%)\n" +
+		"Groceries: €-300.00 -> €-450.00 (€-150.00, +50.00%)\n" +
+		"Streaming: €0.00 -> €-9.99 (€-9.99)\n" +
+		"New Categories: Streaming\n" +
+		"Disappeared Categories: Fun\n" +
+		"Metrics\n" +
+		"Savings Rate: 82.00% -> 78.10% (-3.90 points)\n" +
+		"Average Daily Spend: €-30.00 -> €-23.00 (€7.00, -23.33%)\n"
+	if actual := comparison.String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+	buf := new(bytes.Buffer)
+	if err := comparison.WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Section,Name,Previous,Current,Change,Percent Change\nTotal,Total Income,2000.00,2100.00,100.00,5.00") || !strings.Contains(buf.String(), "\nCategory,Streaming,0.00,-9.99,-9.99,\nMetric,Savings Rate,82.00,78.10,-3.90,\n") {
+		t.Errorf("Expected the deltas as CSV, got %s", buf.String())
+	}
+}
+
+func TestPeriodComparisonReportPerPayer(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "2024-04-10", Amount: currency.NewEuro(-100), Description: "Groceries"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-04-10", Amount: currency.NewEuro(-150), Description: "Groceries"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-04-11", Amount: currency.NewEuro(-50), Description: "Groceries"}, PaidBy: "Charles"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-11", Amount: currency.NewEuro(-500), Description: "Groceries"}, PaidBy: "Charles"},
+	}
+	comparison, err := report.NewPeriodComparisonReport(transactions, "2024-04", "2025-04", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison.Categories) != 1 || comparison.Categories[0].Change().Cmp(currency.NewEuro(-100)) != 0 {
+		t.Errorf("Expected groceries to grow by €-100.00, got %v", comparison.Categories)
+	}
+	if !strings.Contains(comparison.String(), "Expense Per Person\nCharles: €0.00 -> €-50.00 (€-50.00)\nJoe: €-100.00 -> €-150.00 (€-50.00, +50.00%)\n") {
+		t.Errorf("Expected the expense per person, got %s", comparison.String())
+	}
+}
+
+func TestPeriodComparisonReportMatchesWholePeriods(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "2024-12-31", Amount: currency.NewEuro(-40), Description: "Cinema", Category: "Fun"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-01-15", Amount: currency.NewEuro(-100), Description: "Supermarket", Category: "Groceries"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-10-15", Amount: currency.NewEuro(-200), Description: "Supermarket", Category: "Groceries"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-11-15", Amount: currency.NewEuro(-300), Description: "Supermarket", Category: "Groceries"}},
+	}
+	comparison, err := report.NewPeriodComparisonReport(transactions, "2024", "2025-10", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comparison.TotalExpense.Previous.Cmp(currency.NewEuro(-40)) != 0 || comparison.TotalExpense.Current.Cmp(currency.NewEuro(-200)) != 0 {
+		t.Errorf("Expected 2024 to spend €-40.00 and 2025-10 to spend €-200.00, got %v", comparison.TotalExpense)
+	}
+	if _, err := report.NewPeriodComparisonReport(transactions, "2025-1", "2025-10", false); err == nil {
+		t.Error("Expected an error for the period 2025-1, which would match October to December")
+	}
+}
+
+func TestDeltaPercentChange(t *testing.T) {
+	tests := []struct {
+		delta    report.Delta
+		expected float64
+	}{
+		{report.Delta{Name: "Net Income", Previous: currency.NewEuro(-100), Current: currency.NewEuro(100)}, 200},
+		{report.Delta{Name: "Total Income", Previous: currency.NewEuro(2000), Current: currency.NewEuro(1500)}, -25},
+		{report.Delta{Name: "Groceries", Previous: currency.NewEuro(-300), Current: currency.NewEuro(-450), Expense: true}, 50},
+		{report.Delta{Name: "Fun", Previous: currency.NewEuro(-60), Current: currency.NewEuro(-15), Expense: true}, -75},
+	}
+	for _, test := range tests {
+		if percent, ok := test.delta.PercentChange(); !ok || percent != test.expected {
+			t.Errorf("Expected %s to change by %+.2f%%, got %.2f%% (%v)", test.delta.Name, test.expected, percent, ok)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/internal/text"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// DuplicateReason explains why a transaction was considered a duplicate of another
+type DuplicateReason string
+
+// The reasons are listed from most to least certain
+const (
+	ReasonMatchingID DuplicateReason = "matching ID"
+	ReasonExactMatch DuplicateReason = "exact match"
+	ReasonFuzzyMatch DuplicateReason = "fuzzy match"
+)
+
+var duplicateReasonRank = map[DuplicateReason]int{ReasonMatchingID: 0, ReasonExactMatch: 1, ReasonFuzzyMatch: 2}
+
+// DedupOptions configures how the same transaction appearing in more than one report is detected when combining reports.
+// Transactions are only ever compared against transactions from other reports, so repeated purchases within a single
+// report (e.g. two coffees on the same day) are always kept
+type DedupOptions struct {
+	// KeepDuplicates disables deduplication, so that the reports are simply concatenated
+	KeepDuplicates bool
+	// MatchIDs treats transactions with the same bank-assigned ID as duplicates. Transactions which both have an ID
+	// are never considered duplicates if their IDs differ
+	MatchIDs bool
+	// DateWindow, if positive, also treats transactions as duplicates when they have the same amount and payer,
+	// similar descriptions, and dates no more than DateWindow days apart
+	DateWindow int
+}
+
+// DefaultDedupOptions drops exact matches on (time, amount, description, payer) and transactions with matching bank IDs
+func DefaultDedupOptions() DedupOptions {
+	return DedupOptions{MatchIDs: true}
+}
+
+// Duplicate records a transaction which was dropped because it duplicates one which was kept
+// For transactions from basic reports, PaidBy is empty
+type Duplicate struct {
+	Kept    transaction.PayerTransaction
+	Dropped transaction.PayerTransaction
+	Reason  DuplicateReason
+}
+
+// String describes the dropped transaction and the transaction it duplicated
+func (d Duplicate) String() string {
+	return fmt.Sprintf("dropped %s (%s of %s)", describeTransaction(d.Dropped), d.Reason, describeTransaction(d.Kept))
+}
+
+func describeTransaction(tx transaction.PayerTransaction) string {
+	description := fmt.Sprintf("%s,%s,%s", tx.Time, tx.Amount.String(), tx.Description)
+	if tx.PaidBy != "" {
+		description += "," + tx.PaidBy
+	}
+	return description
+}
+
+// dedupTransactions merges the transactions from several sources, dropping those which duplicate a transaction from
+// an earlier source. Each kept transaction can absorb at most one duplicate from each other source
+func dedupTransactions(sources [][]transaction.PayerTransaction, opts DedupOptions) ([]transaction.PayerTransaction, []Duplicate) {
+	var kept []transaction.PayerTransaction
+	var keptSource []int
+	matched := make(map[[2]int]bool)
+	var duplicates []Duplicate
+	for source, transactions := range sources {
+		for _, tx := range transactions {
+			if !opts.KeepDuplicates {
+				match, reason := -1, DuplicateReason("")
+				for idx, candidate := range kept {
+					if keptSource[idx] == source || matched[[2]int{idx, source}] {
+						continue
+					}
+					candidateReason := opts.duplicateReason(candidate, tx)
+					if candidateReason != "" && (match < 0 || duplicateReasonRank[candidateReason] < duplicateReasonRank[reason]) {
+						match, reason = idx, candidateReason
+					}
+				}
+				if match >= 0 {
+					matched[[2]int{match, source}] = true
+					duplicates = append(duplicates, Duplicate{Kept: kept[match], Dropped: tx, Reason: reason})
+					continue
+				}
+			}
+			kept = append(kept, tx)
+			keptSource = append(keptSource, source)
+		}
+	}
+	return kept, duplicates
+}
+
+// duplicateReason returns why b duplicates a, or the empty string if it does not
+func (opts DedupOptions) duplicateReason(a, b transaction.PayerTransaction) DuplicateReason {
+	if opts.MatchIDs && a.ID != "" && b.ID != "" {
+		if a.ID == b.ID {
+			return ReasonMatchingID
+		}
+		return ""
+	}
+	if a.Amount.Cmp(b.Amount) != 0 || a.PaidBy != b.PaidBy {
+		return ""
+	}
+	if a.Time == b.Time && a.Description == b.Description {
+		return ReasonExactMatch
+	}
+	if opts.DateWindow > 0 && similarDescriptions(a.Description, b.Description) && withinDays(a.Time, b.Time, opts.DateWindow) {
+		return ReasonFuzzyMatch
+	}
+	return ""
+}
+
+// similarDescriptions reports whether the normalized descriptions are equal, or one is a prefix of the other
+func similarDescriptions(a, b string) bool {
+	a, b = text.Normalize(a), text.Normalize(b)
+	if a == "" || b == "" {
+		return a == b
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// withinDays reports whether both times are dates no more than days apart
+func withinDays(a, b string, days int) bool {
+	dateA, err := transaction.ParseTime(a)
+	if err != nil {
+		return false
+	}
+	dateB, err := transaction.ParseTime(b)
+	if err != nil {
+		return false
+	}
+	diff := dateA.Sub(dateB)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(days)*24*time.Hour
+}
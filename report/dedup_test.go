@@ -0,0 +1,90 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func TestCombineReportsWithOptionsDropsOverlappingTransactions(t *testing.T) {
+	reportName := "Test"
+	report1, err := report.ReadDefaultBudgetReportFromFile(reportName, "../testdata/defaultreport.csv")
+	if err != nil {
+		t.Fatalf("Failed while reading report from file: %v", err)
+	}
+	report2, err := report.ReadDefaultBudgetReportFromFile(reportName, "../testdata/defaultreport.csv")
+	if err != nil {
+		t.Fatalf("Failed while reading report from file: %v", err)
+	}
+	actual, duplicates, err := report.CombineReportsWithOptions(reportName, []report.Report{report1, report2}, report.DefaultDedupOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualBasicReport, ok := actual.(report.BasicReport)
+	if !ok {
+		t.Fatalf("Expected basic report, got %T", actual)
+	}
+	if len(duplicates) != 3 {
+		t.Errorf("Expected 3 duplicates, got %d: %v", len(duplicates), duplicates)
+	}
+	if actualBasicReport.TotalIncome.Cmp(report1.TotalIncome) != 0 {
+		t.Errorf("Expected total income %s, got %s", report1.TotalIncome.String(), actualBasicReport.TotalIncome.String())
+	}
+	if actualBasicReport.TotalExpense.Cmp(report1.TotalExpense) != 0 {
+		t.Errorf("Expected total expense %s, got %s", report1.TotalExpense.String(), actualBasicReport.TotalExpense.String())
+	}
+}
+
+func TestCombineReportsWithOptionsKeepsRepeatsWithinAReport(t *testing.T) {
+	coffee := transaction.BasicTransaction{Time: "2025-01-02", Amount: currency.NewEuro(-3), Description: "Coffee"}
+	report1 := report.NewBasicBudgetReport("January", []transaction.BasicTransaction{coffee, coffee})
+	report2 := report.NewBasicBudgetReport("January", []transaction.BasicTransaction{coffee})
+	actual, duplicates, err := report.CombineBasicReportsWithOptions("January", []report.Report{report1, report2}, report.DefaultDedupOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(duplicates) != 1 {
+		t.Errorf("Expected 1 duplicate, got %d", len(duplicates))
+	}
+	expected := currency.NewEuro(-6)
+	if actual.TotalExpense.Cmp(expected) != 0 {
+		t.Errorf("Expected total expense %s, got %s", expected.String(), actual.TotalExpense.String())
+	}
+}
+
+func TestCombineReportsWithOptionsMatching(t *testing.T) {
+	original := transaction.BasicTransaction{Time: "2025-01-02", Amount: currency.NewEuro(-25), Description: "REWE SAGT DANKE 1234", ID: "A1"}
+	tests := []struct {
+		name      string
+		candidate transaction.BasicTransaction
+		opts      report.DedupOptions
+		expected  report.DuplicateReason
+	}{
+		{"same ID", transaction.BasicTransaction{Time: "2025-01-03", Amount: currency.NewEuro(-25), Description: "Groceries", ID: "A1"}, report.DefaultDedupOptions(), report.ReasonMatchingID},
+		{"different ID", transaction.BasicTransaction{Time: "2025-01-02", Amount: currency.NewEuro(-25), Description: "REWE SAGT DANKE 1234", ID: "A2"}, report.DefaultDedupOptions(), ""},
+		{"exact", transaction.BasicTransaction{Time: "2025-01-02", Amount: currency.NewEuro(-25), Description: "REWE SAGT DANKE 1234"}, report.DefaultDedupOptions(), report.ReasonExactMatch},
+		{"fuzzy disabled", transaction.BasicTransaction{Time: "04.01.2025", Amount: currency.NewEuro(-25), Description: "Rewe sagt danke 9876"}, report.DefaultDedupOptions(), ""},
+		{"fuzzy", transaction.BasicTransaction{Time: "04.01.2025", Amount: currency.NewEuro(-25), Description: "Rewe sagt danke 9876"}, report.DedupOptions{DateWindow: 3}, report.ReasonFuzzyMatch},
+		{"outside window", transaction.BasicTransaction{Time: "2025-01-09", Amount: currency.NewEuro(-25), Description: "Rewe sagt danke 9876"}, report.DedupOptions{DateWindow: 3}, ""},
+		{"keep duplicates", original, report.DedupOptions{KeepDuplicates: true}, ""},
+	}
+	for _, test := range tests {
+		report1 := report.NewBasicBudgetReport("January", []transaction.BasicTransaction{original})
+		report2 := report.NewBasicBudgetReport("January", []transaction.BasicTransaction{test.candidate})
+		actual, duplicates, err := report.CombineBasicReportsWithOptions("January", []report.Report{report1, report2}, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.expected == "" {
+			if len(duplicates) != 0 || len(actual.Transactions()) != 2 {
+				t.Errorf("%s: expected no duplicates, got %v", test.name, duplicates)
+			}
+			continue
+		}
+		if len(duplicates) != 1 || duplicates[0].Reason != test.expected {
+			t.Errorf("%s: expected one duplicate with reason %q, got %v", test.name, test.expected, duplicates)
+		}
+	}
+}
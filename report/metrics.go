@@ -0,0 +1,174 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// CategoryClass classifies the expenses of a category, and its sub-categories, as essential or discretionary, and as
+// fixed or variable costs
+type CategoryClass struct {
+	Category  string
+	Essential bool
+	Fixed     bool
+}
+
+// Classification classifies categories for the metrics. A category takes the class of its most specific classified
+// ancestor
+type Classification []CategoryClass
+
+// ReadClassificationFromFile reads a classification from a CSV file with the columns Category,Class, where the class is
+// essential or discretionary, and optionally Fixed
+func ReadClassificationFromFile(path string) (Classification, error) {
+	var classification Classification
+	err := csvfile.Read(path, []string{"Category", "Class"}, func(row csvfile.Row) error {
+		class := CategoryClass{Category: row.Get("Category")}
+		if class.Category == "" {
+			return fmt.Errorf("missing category")
+		}
+		switch strings.ToLower(row.Get("Class")) {
+		case "essential":
+			class.Essential = true
+		case "discretionary":
+		default:
+			return fmt.Errorf("invalid Class: expected essential or discretionary, got %q", row.Get("Class"))
+		}
+		var err error
+		if class.Fixed, err = row.Bool("Fixed"); err != nil {
+			return err
+		}
+		classification = append(classification, class)
+		return nil
+	})
+	return classification, err
+}
+
+// Classify returns the class of the category, and false if neither it nor any of its parents are classified
+func (c Classification) Classify(category string) (CategoryClass, bool) {
+	var best CategoryClass
+	found := false
+	for _, class := range c {
+		if category != class.Category && !strings.HasPrefix(category, class.Category+CategorySeparator) {
+			continue
+		}
+		if !found || len(class.Category) > len(best.Category) {
+			best, found = class, true
+		}
+	}
+	return best, found
+}
+
+// MetricsOptions configures the metrics which need more than the transactions
+type MetricsOptions struct {
+	// Classification is needed for the essential, discretionary and fixed-cost metrics
+	Classification Classification
+	// Balance is the current balance across accounts, needed for the months of runway, or nil if unknown
+	Balance *currency.Euro
+}
+
+// Metrics are ratios which summarise a period's finances, and are comparable across periods
+type Metrics struct {
+	// SavingsRate is the net income as a percentage of the income
+	SavingsRate float64 `json:"savingsRate"`
+	// EssentialShare and DiscretionaryShare are percentages of the expenses. Unclassified expenses are in neither
+	EssentialShare     float64 `json:"essentialShare"`
+	DiscretionaryShare float64 `json:"discretionaryShare"`
+	// FixedCostRatio is the fixed expenses as a percentage of the income
+	FixedCostRatio float64 `json:"fixedCostRatio"`
+	// AverageDailySpend is the expense per day from the first to the last dated transaction
+	AverageDailySpend currency.Euro `json:"averageDailySpend"`
+	// MonthsOfRunway is how many months the balance would last at the average daily spend
+	MonthsOfRunway float64 `json:"monthsOfRunway"`
+	Classified     bool    `json:"-"`
+	HasRunway      bool    `json:"-"`
+}
+
+// CalculateMetrics computes the metrics of the transactions. Ratios whose denominator is zero are zero
+func CalculateMetrics(transactions []transaction.BasicTransaction, opts MetricsOptions) Metrics {
+	var income, expense, essential, discretionary, fixed currency.Euro
+	var first, last time.Time
+	for _, tx := range transactions {
+		if date, err := tx.Date(); err == nil {
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+			if date.After(last) {
+				last = date
+			}
+		}
+		if tx.IsIncome() {
+			income = currency.AddEuros(income, tx.Amount)
+		}
+		if !tx.IsExpense() {
+			continue
+		}
+		expense = currency.AddEuros(expense, tx.Amount)
+		if class, ok := opts.Classification.Classify(tx.EffectiveCategory()); ok {
+			if class.Essential {
+				essential = currency.AddEuros(essential, tx.Amount)
+			} else {
+				discretionary = currency.AddEuros(discretionary, tx.Amount)
+			}
+			if class.Fixed {
+				fixed = currency.AddEuros(fixed, tx.Amount)
+			}
+		}
+	}
+	metrics := Metrics{Classified: len(opts.Classification) > 0, HasRunway: opts.Balance != nil}
+	if income.Cents() != 0 {
+		metrics.SavingsRate = percentage(currency.AddEuros(income, expense), income)
+		metrics.FixedCostRatio = -percentage(fixed, income)
+	}
+	if expense.Cents() != 0 {
+		metrics.EssentialShare = percentage(essential, expense)
+		metrics.DiscretionaryShare = percentage(discretionary, expense)
+	}
+	if !first.IsZero() {
+		days := int64(last.Sub(first).Hours()/24) + 1
+		metrics.AverageDailySpend = currency.NewEuroFromCents(int64(math.Round(float64(expense.Cents()) / float64(days))))
+	}
+	if opts.Balance != nil && metrics.AverageDailySpend.Cents() != 0 {
+		monthlySpend := -float64(metrics.AverageDailySpend.Cents()) * 365.25 / 12
+		metrics.MonthsOfRunway = float64(opts.Balance.Cents()) / monthlySpend
+	}
+	return metrics
+}
+
+// writeMetrics writes the metrics, leaving out those which were not computed
+func writeMetrics(str *strings.Builder, metrics *Metrics) {
+	if metrics == nil {
+		return
+	}
+	str.WriteString("Metrics\n")
+	str.WriteString(fmt.Sprintf("Savings Rate: %.2f%%\n", metrics.SavingsRate))
+	if metrics.Classified {
+		str.WriteString(fmt.Sprintf("Essential: %.2f%%, Discretionary: %.2f%% of expenses\n", metrics.EssentialShare, metrics.DiscretionaryShare))
+		str.WriteString(fmt.Sprintf("Fixed-Cost Ratio: %.2f%% of income\n", metrics.FixedCostRatio))
+	}
+	str.WriteString(fmt.Sprintf("Average Daily Spend: %s\n", metrics.AverageDailySpend.String()))
+	if metrics.HasRunway {
+		str.WriteString(fmt.Sprintf("Months of Runway: %.1f\n", metrics.MonthsOfRunway))
+	}
+}
+
+// ComputeReportMetrics computes the metrics of a report as described by CalculateMetrics, returning the report with its
+// metrics set
+func ComputeReportMetrics(r Report, opts MetricsOptions) Report {
+	switch r := r.(type) {
+	case BasicReport:
+		metrics := CalculateMetrics(r.lines(), opts)
+		r.Metrics = &metrics
+		return r
+	case MultiPayerReport:
+		metrics := CalculateMetrics(r.lines(), opts)
+		r.Metrics = &metrics
+		return r
+	}
+	return r
+}
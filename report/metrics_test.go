@@ -0,0 +1,63 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+)
+
+func TestClassify(t *testing.T) {
+	classification, err := report.ReadClassificationFromFile("../testdata/classification.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"Food": true, "Food:Groceries": true, "Food:Eating Out": false, "Food:Eating Out:Pizza": false, "Fun": false}
+	for category, essential := range expected {
+		class, ok := classification.Classify(category)
+		if !ok || class.Essential != essential {
+			t.Errorf("Expected %s to be essential: %t, got %+v (%t)", category, essential, class, ok)
+		}
+	}
+	if _, ok := classification.Classify("Foods"); ok {
+		t.Errorf("Expected Foods to be unclassified")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	classification, err := report.ReadClassificationFromFile("../testdata/classification.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := currency.NewEuro(1000)
+	r := report.ComputeReportMetrics(readReport(t, "../testdata/envelopetransactions.csv"), report.MetricsOptions{Classification: classification, Balance: &balance})
+	expected := "Metrics\n" +
+		"Savings Rate: 32.50%\n" +
+		"Essential: 75.93%, Discretionary: 23.33% of expenses\n" +
+		"Fixed-Cost Ratio: 43.75% of income\n" +
+		"Average Daily Spend: €-62.79\n" +
+		"Months of Runway: 0.5\n"
+	if !strings.Contains(r.String(), expected) {
+		t.Errorf("Expected the metrics\n%s\ngot\n%s", expected, r.String())
+	}
+	buf := new(bytes.Buffer)
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"savingsRate": 32.5,`) || !strings.Contains(buf.String(), `"averageDailySpend": -62.79,`) {
+		t.Errorf("Expected the metrics in the JSON output, got %s", buf.String())
+	}
+	if withoutOptions := report.CalculateMetrics(nil, report.MetricsOptions{}); withoutOptions.SavingsRate != 0 || withoutOptions.AverageDailySpend.Cents() != 0 {
+		t.Errorf("Expected empty metrics without transactions, got %+v", withoutOptions)
+	}
+}
+
+func readReport(t *testing.T, path string) report.Report {
+	r, err := report.ReadBudgetReportFromFile(path, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
@@ -1,15 +1,12 @@
 package report
 
 import (
-	"encoding/csv"
-	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"math"
 	"os"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/kevslinger/budget/currency"
@@ -25,17 +22,27 @@ type MultiPayerReport struct {
 	NetIncomePerPayer    map[string]currency.Euro
 	TotalIncomePerPayer  map[string]currency.Euro
 	TotalExpensePerPayer map[string]currency.Euro
-	transactions         []transaction.PayerTransaction
+	// TotalInvested is the money paid into investments, which counts as saved rather than spent
+	TotalInvested currency.Euro
+	// Metrics are the ratios computed by ComputeReportMetrics, or nil if it was not run
+	Metrics *Metrics
+	// Anomalies are the surprises found by DetectReportAnomalies, if it was run
+	Anomalies    []Anomaly
+	transactions []transaction.PayerTransaction
 }
 
+// NewMultiPayerBudgetReport creates a new shared report with a given reportName, calculating the total income, total expense,
+// and net income, both overall and per payer. Transfers between accounts count as neither income nor expense, and
+// investments count towards the total invested
 func NewMultiPayerBudgetReport(reportName string, transactions []transaction.PayerTransaction) MultiPayerReport {
 	totalIncomePerPayer := make(map[string]currency.Euro)
 	totalExpensePerPayer := make(map[string]currency.Euro)
-	for _, transaction := range transactions {
-		if transaction.Amount.Cmp(currency.NewEuro(0.0)) < 0 {
+	lines := splitPayerLines(transactions)
+	for _, transaction := range lines {
+		if transaction.IsExpense() {
 			totalExpensePerPayer[transaction.PaidBy] = currency.AddEuros(totalExpensePerPayer[transaction.PaidBy], transaction.Amount)
-		} else {
-			totalIncomePerPayer[transaction.PaidBy] = currency.AddEuros(totalExpensePerPayer[transaction.PaidBy], transaction.Amount)
+		} else if transaction.IsIncome() {
+			totalIncomePerPayer[transaction.PaidBy] = currency.AddEuros(totalIncomePerPayer[transaction.PaidBy], transaction.Amount)
 		}
 	}
 	netIncomePerPayer := make(map[string]currency.Euro)
@@ -49,66 +56,65 @@ func NewMultiPayerBudgetReport(reportName string, transactions []transaction.Pay
 		netIncomePerPayer[payer] = currency.AddEuros(netIncomePerPayer[payer], totalExpensePerPayer[payer])
 		totalExpense = currency.AddEuros(totalExpense, totalExpensePerPayer[payer])
 	}
-	return MultiPayerReport{Name: reportName, NetIncome: currency.AddEuros(totalIncome, totalExpense), TotalIncome: totalIncome, TotalExpense: totalExpense, NetIncomePerPayer: netIncomePerPayer, TotalIncomePerPayer: totalIncomePerPayer, TotalExpensePerPayer: totalExpensePerPayer, transactions: transactions}
+	return MultiPayerReport{Name: reportName, NetIncome: currency.AddEuros(totalIncome, totalExpense), TotalIncome: totalIncome, TotalExpense: totalExpense, NetIncomePerPayer: netIncomePerPayer, TotalIncomePerPayer: totalIncomePerPayer, TotalExpensePerPayer: totalExpensePerPayer, TotalInvested: totalInvested(basicTransactions(lines)), transactions: transactions}
 }
 
+// ReadMultiPayerBudgetReportFromFile reads in a CSV file with transactions and who earned/paid them, and parses them to create a report
 func ReadMultiPayerBudgetReportFromFile(reportName string, path string) (MultiPayerReport, error) {
-	file, err := os.Open(path)
+	transactions, _, err := ReadTransactionsFromFile(path)
 	if err != nil {
-		return MultiPayerReport{}, fmt.Errorf("error opening budget report file: %w", err)
-	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-	line, err := reader.Read()
-	if err != nil {
-		return MultiPayerReport{}, fmt.Errorf("error reading budet report file: %w", err)
-	}
-	var transactions []transaction.PayerTransaction
-	for err == nil {
-		time, amount, description, payer := line[0], line[1], line[2], line[3]
-		// skip header
-		if strings.ToLower(time) == "time" && strings.ToLower(amount) == "amount" && strings.ToLower(description) == "description" {
-			line, err = reader.Read()
-			continue
-		}
-		amountFloat, parseErr := strconv.ParseFloat(amount, 64)
-		if parseErr != nil {
-			return MultiPayerReport{}, fmt.Errorf("error parsing a transaction: %w", parseErr)
-		}
-		transactions = append(transactions, transaction.PayerTransaction{Time: time, Amount: currency.NewEuro(amountFloat), Description: description, PaidBy: payer})
-		line, err = reader.Read()
-	}
-	if !errors.Is(err, io.EOF) {
-		return MultiPayerReport{}, fmt.Errorf("error reading currency report file: %w", err)
+		return MultiPayerReport{}, err
 	}
-
 	return NewMultiPayerBudgetReport(reportName, transactions), nil
 }
 
+// CombineMultiPayerReports merges multi-payer reports into a single report, keeping every transaction
 func CombineMultiPayerReports(reportName string, reports []Report) (MultiPayerReport, error) {
-	transactions := make([]transaction.PayerTransaction, 0)
+	combined, _, err := CombineMultiPayerReportsWithOptions(reportName, reports, DedupOptions{KeepDuplicates: true})
+	return combined, err
+}
+
+// CombineMultiPayerReportsWithOptions merges multi-payer reports into a single report, dropping transactions which
+// appear in more than one report according to opts. It returns the duplicates which were dropped
+func CombineMultiPayerReportsWithOptions(reportName string, reports []Report, opts DedupOptions) (MultiPayerReport, []Duplicate, error) {
+	sources := make([][]transaction.PayerTransaction, 0, len(reports))
 	for _, r := range reports {
 		multiPayerReport, ok := r.(MultiPayerReport)
 		if !ok {
-			return MultiPayerReport{}, fmt.Errorf("expected MultiPayerReport, got %T", r)
+			return MultiPayerReport{}, nil, fmt.Errorf("expected MultiPayerReport, got %T", r)
 		}
-		transactions = append(transactions, multiPayerReport.Transactions()...)
+		sources = append(sources, multiPayerReport.Transactions())
 	}
-	return NewMultiPayerBudgetReport(reportName, transactions), nil
+	transactions, duplicates := dedupTransactions(sources, opts)
+	return NewMultiPayerBudgetReport(reportName, transactions), duplicates, nil
 }
 
 // CalculateTotalExpensePerDescription aggregates all expenses by category, and returns this data as a map
 func (r MultiPayerReport) CalculateTotalExpensePerDescription() map[string]currency.Euro {
-	expensePerDescription := make(map[string]currency.Euro)
-	for _, transaction := range r.transactions {
-		if transaction.Amount.Cmp(currency.NewEuro(0.0)) < 0 {
-			if _, ok := expensePerDescription[transaction.Description]; !ok {
-				expensePerDescription[transaction.Description] = currency.NewEuro(0.0)
-			}
-			expensePerDescription[transaction.Description] = currency.AddEuros(expensePerDescription[transaction.Description], transaction.Amount)
-		}
-	}
-	return expensePerDescription
+	return r.CalculateTotalExpensePerDescriptionAtDepth(0)
+}
+
+// CalculateTotalExpensePerDescriptionAtDepth aggregates all expenses by category, rolling hierarchical categories
+// such as "Food:Groceries" up to their first depth levels. A depth of zero or less keeps the full categories
+func (r MultiPayerReport) CalculateTotalExpensePerDescriptionAtDepth(depth int) map[string]currency.Euro {
+	return totalExpensePerCategory(r.lines(), depth)
+}
+
+// CalculateTotalExpensePer aggregates all expenses by a dimension, such as their payee, and returns this data as a map
+func (r MultiPayerReport) CalculateTotalExpensePer(dimension Dimension) map[string]currency.Euro {
+	return totalExpensePer(r.lines(), dimension)
+}
+
+// CalculateTotalExpensePerTag aggregates all tagged expenses by tag, and returns this data as a map
+// Expenses with several tags count towards each of them
+func (r MultiPayerReport) CalculateTotalExpensePerTag() map[string]currency.Euro {
+	return totalExpensePerTag(r.lines())
+}
+
+// CalculateBalancePerAccount sums the transactions of each account, including transfers, giving the net change in
+// each account's balance over the report's period
+func (r MultiPayerReport) CalculateBalancePerAccount() map[string]currency.Euro {
+	return balancePerAccount(r.lines())
 }
 
 // Save saves the report's transactions to a CSV file
@@ -148,6 +154,7 @@ func (r MultiPayerReport) String() string {
 	var str strings.Builder
 	str.WriteString(fmt.Sprintf("currency Report for the period %s\n", r.Name))
 	str.WriteString(fmt.Sprintf("Total Income: %s, Total Expense: %s, Net Income: %s\n", r.TotalIncome.String(), r.TotalExpense.String(), r.NetIncome.String()))
+	writeTotalInvested(&str, r.TotalInvested)
 	str.WriteString("Total Income Per Person (%% of total income)\n")
 	sortedNameKeys := sortKeys(maps.Keys(r.TotalIncomePerPayer))
 	for _, name := range sortedNameKeys {
@@ -158,29 +165,36 @@ func (r MultiPayerReport) String() string {
 		str.WriteString(fmt.Sprintf("%s: %s (%.2f%%)\n", name, r.TotalExpensePerPayer[name], 100*float64(r.TotalExpensePerPayer[name].Cents())/float64(r.TotalExpense.Cents())))
 	}
 	str.WriteString("Total Expense Per Category (%% of total expenses)\n")
-	expensePerDescription := r.CalculateTotalExpensePerDescription()
-	sortedKeys := sortKeys(maps.Keys(expensePerDescription))
-	for _, key := range sortedKeys {
-		str.WriteString(fmt.Sprintf("%s: %s (%.2f%%)\n", key, expensePerDescription[key].String(), 100*float64(expensePerDescription[key].Cents())/float64(r.TotalExpense.Cents())))
-	}
-	str.WriteString("Time,Amount,Description,Name\n")
+	writeExpenseTree(&str, r.CalculateTotalExpensePerDescription(), r.TotalExpense)
+	writeExpensePerTag(&str, r.CalculateTotalExpensePerTag(), r.TotalExpense)
+	writeBalancePerAccount(&str, r.CalculateBalancePerAccount())
+	writeMetrics(&str, r.Metrics)
+	writeAnomalies(&str, r.Anomalies)
+	columns := usedColumns(basicTransactions(r.transactions))
+	str.WriteString("Time,Amount,Description,Name" + columnHeader(columns) + "\n")
 	for _, income := range r.SortIncomes() {
-		str.WriteString(fmt.Sprintf("%s,%s,%s,%s\n", income.Time, income.Amount.String(), income.Description, income.PaidBy))
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s%s\n", income.Time, income.Amount.String(), income.Description, income.PaidBy, columnValues(columns, income.BasicTransaction)))
 	}
 	for _, expense := range r.SortExpenses() {
-		str.WriteString(fmt.Sprintf("%s,%s,%s,%s\n", expense.Time, expense.Amount.String(), expense.Description, expense.PaidBy))
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s%s\n", expense.Time, expense.Amount.String(), expense.Description, expense.PaidBy, columnValues(columns, expense.BasicTransaction)))
 	}
 	return str.String()
 }
 
+// WriteJSON writes the report's totals, transactions and anomalies as JSON
+func (r MultiPayerReport) WriteJSON(writer io.Writer) error {
+	return writeJSON(writer, jsonReport[transaction.PayerTransaction]{Name: r.Name, TotalIncome: r.TotalIncome, TotalExpense: r.TotalExpense, NetIncome: r.NetIncome, TotalInvested: r.TotalInvested, Metrics: r.Metrics, Transactions: r.transactions, Anomalies: r.Anomalies})
+}
+
 // WriteCSV writes the report to a CSV
 func (r MultiPayerReport) WriteCSV(writer io.Writer) error {
-	fmt.Fprint(writer, "Time,Amount,Description,Name")
+	columns := usedColumns(basicTransactions(r.transactions))
+	fmt.Fprint(writer, "Time,Amount,Description,Name"+columnHeader(columns))
 	for _, income := range r.SortIncomes() {
-		fmt.Fprintf(writer, "\n%s,%.2f,%s,%s", income.Time, float64(income.Amount.Cents())/100, income.Description, income.PaidBy)
+		fmt.Fprintf(writer, "\n%s,%.2f,%s,%s%s", income.Time, float64(income.Amount.Cents())/100, csvField(income.Description), income.PaidBy, columnValues(columns, income.BasicTransaction))
 	}
 	for _, expense := range r.SortExpenses() {
-		fmt.Fprintf(writer, "\n%s,%.2f,%s,%s", expense.Time, float64(expense.Amount.Cents())/100, expense.Description, expense.PaidBy)
+		fmt.Fprintf(writer, "\n%s,%.2f,%s,%s%s", expense.Time, float64(expense.Amount.Cents())/100, csvField(expense.Description), expense.PaidBy, columnValues(columns, expense.BasicTransaction))
 	}
 	return nil
 }
@@ -218,11 +232,26 @@ func sortPayerTransactions(transactions []transaction.PayerTransaction) []transa
 	return transactions
 }
 
+// lines returns the report's transactions, without who earned/paid them, with every split transaction replaced by its
+// child lines
+func (r MultiPayerReport) lines() []transaction.BasicTransaction {
+	return basicTransactions(splitPayerLines(r.transactions))
+}
+
 // Transactions returns a copy of the transactions from the report
 func (r MultiPayerReport) Transactions() []transaction.PayerTransaction {
 	var transactions []transaction.PayerTransaction
 	for _, tx := range r.transactions {
-		transactions = append(transactions, transaction.PayerTransaction{Time: tx.Time, Amount: tx.Amount, Description: tx.Description, PaidBy: tx.PaidBy})
+		transactions = append(transactions, tx)
 	}
 	return transactions
 }
+
+// basicTransactions strips who earned/paid from each transaction
+func basicTransactions(transactions []transaction.PayerTransaction) []transaction.BasicTransaction {
+	basic := make([]transaction.BasicTransaction, 0, len(transactions))
+	for _, tx := range transactions {
+		basic = append(basic, tx.BasicTransaction)
+	}
+	return basic
+}
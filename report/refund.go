@@ -0,0 +1,133 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// resolveRefunds gives uncategorised refunds the category of the expense they pay back, so they net against it. A
+// refund whose RefundOf is not the ID of a transaction pays back a category, if it is the category, or a parent of the
+// category, of a transaction. It returns the indexes of the refunds whose RefundOf is neither, which are left
+// uncategorised
+func resolveRefunds(transactions []transaction.PayerTransaction) []int {
+	ids := make(map[string]string)
+	categories := make(map[string]bool)
+	for _, tx := range transactions {
+		if tx.ID != "" {
+			ids[tx.ID] = tx.EffectiveCategory()
+		}
+		if tx.RefundOf != "" {
+			continue
+		}
+		parts := strings.Split(tx.EffectiveCategory(), CategorySeparator)
+		for i := range parts {
+			categories[strings.Join(parts[:i+1], CategorySeparator)] = true
+		}
+	}
+	var unresolved []int
+	for i, tx := range transactions {
+		if tx.RefundOf == "" || tx.Category != "" {
+			continue
+		}
+		if category, ok := ids[tx.RefundOf]; ok {
+			transactions[i].Category = category
+		} else if categories[tx.RefundOf] {
+			transactions[i].Category = tx.RefundOf
+		} else {
+			unresolved = append(unresolved, i)
+		}
+	}
+	return unresolved
+}
+
+// ResolveRefunds resolves the refunds of the transactions of several report files, which may pay back an expense in
+// another file. It returns an error for the first refund whose RefundOf is neither the ID of a transaction nor a
+// category
+func ResolveRefunds(transactions []transaction.PayerTransaction) error {
+	if unresolved := resolveRefunds(transactions); len(unresolved) > 0 {
+		return fmt.Errorf("error resolving a refund: %q is neither the ID of a transaction nor a category", transactions[unresolved[0]].RefundOf)
+	}
+	return nil
+}
+
+// Reimbursement is a reimbursable expense and how much of it has been paid back
+type Reimbursement struct {
+	Expense transaction.BasicTransaction
+	// Reimbursed is the amount paid back so far, as a positive amount
+	Reimbursed currency.Euro
+}
+
+// Outstanding returns the amount still to be paid back, as a positive amount
+func (r Reimbursement) Outstanding() currency.Euro {
+	return currency.SubtractEuros(currency.SubtractEuros(currency.NewEuro(0.0), r.Expense.Amount), r.Reimbursed)
+}
+
+// String describes the expense, and how much of it has been and is still to be paid back
+func (r Reimbursement) String() string {
+	return fmt.Sprintf("%s,%s,%s: %s reimbursed, %s outstanding", r.Expense.Time, r.Expense.Amount.String(), r.Expense.Description, r.Reimbursed.String(), r.Outstanding().String())
+}
+
+// Reimbursements matches the refunds to the reimbursable expenses they pay back. Refunds of an expense's ID pay back
+// that expense, and refunds of a category pay back the reimbursable expenses in that category, including its
+// sub-categories, oldest first. The expenses are returned in date order, followed by those whose time is not a date
+func Reimbursements(transactions []transaction.BasicTransaction) []Reimbursement {
+	var reimbursements []Reimbursement
+	for _, tx := range transactions {
+		if tx.Reimbursable && tx.IsExpense() && !tx.IsRefund() {
+			reimbursements = append(reimbursements, Reimbursement{Expense: tx})
+		}
+	}
+	slices.SortStableFunc(reimbursements, func(a, b Reimbursement) int {
+		aDate, aErr := transaction.ParseTime(a.Expense.Time)
+		bDate, bErr := transaction.ParseTime(b.Expense.Time)
+		switch {
+		case aErr != nil && bErr != nil:
+			return 0
+		case aErr != nil:
+			return 1
+		case bErr != nil:
+			return -1
+		}
+		return aDate.Compare(bDate)
+	})
+	for _, refund := range transactions {
+		if !refund.IsRefund() {
+			continue
+		}
+		remaining := refund.Amount
+		for i, r := range reimbursements {
+			if remaining.Cents() <= 0 {
+				break
+			}
+			category := r.Expense.EffectiveCategory()
+			if r.Expense.ID != refund.RefundOf && category != refund.RefundOf && !strings.HasPrefix(category, refund.RefundOf+CategorySeparator) {
+				continue
+			}
+			amount := r.Outstanding()
+			if amount.Cmp(remaining) > 0 {
+				amount = remaining
+			}
+			if amount.Cents() <= 0 {
+				continue
+			}
+			reimbursements[i].Reimbursed = currency.AddEuros(reimbursements[i].Reimbursed, amount)
+			remaining = currency.SubtractEuros(remaining, amount)
+		}
+	}
+	return reimbursements
+}
+
+// OutstandingReimbursements returns the reimbursable expenses which have not been fully paid back
+func OutstandingReimbursements(transactions []transaction.BasicTransaction) []Reimbursement {
+	var outstanding []Reimbursement
+	for _, r := range Reimbursements(transactions) {
+		if r.Outstanding().Cents() > 0 {
+			outstanding = append(outstanding, r)
+		}
+	}
+	return outstanding
+}
@@ -0,0 +1,83 @@
+package report_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func TestRefundsNetAgainstExpenses(t *testing.T) {
+	r, err := report.ReadDefaultBudgetReportFromFile("refunds", "../testdata/refunds.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TotalIncome.Cmp(currency.NewEuro(2500)) != 0 || r.TotalExpense.Cmp(currency.NewEuro(-165)) != 0 {
+		t.Errorf("Expected refunds to reduce the expenses rather than count as income, got %s and %s", r.TotalIncome, r.TotalExpense)
+	}
+	expected := map[string]currency.Euro{"Clothing": currency.NewEuro(0), "Work:Travel": currency.NewEuro(-100), "Work:Meals": currency.NewEuro(-40), "Food": currency.NewEuro(-25)}
+	if diff := cmp.Diff(expected, r.CalculateTotalExpensePerDescription(), cmp.Comparer(func(a, b currency.Euro) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("Expected the refunds to net against their categories, got %s", diff)
+	}
+}
+
+func TestOutstandingReimbursements(t *testing.T) {
+	r, err := report.ReadDefaultBudgetReportFromFile("refunds", "../testdata/refunds.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outstanding := report.OutstandingReimbursements(r.Transactions())
+	var actual []string
+	for _, reimbursement := range outstanding {
+		actual = append(actual, reimbursement.String())
+	}
+	expected := []string{
+		"2025-03-12,€-150.00,Hotel: €50.00 reimbursed, €100.00 outstanding",
+		"2025-03-14,€-40.00,Team lunch: €0.00 reimbursed, €40.00 outstanding",
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Expected the hotel and lunch to be outstanding, got %s", diff)
+	}
+}
+
+func TestResolveRefundsAcrossFiles(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-28", Amount: currency.NewEuro(80), Description: "Shoes returned", RefundOf: "tx-2"}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-02-27", Amount: currency.NewEuro(-80), Description: "Shoes", ID: "tx-2", Category: "Clothing"}},
+	}
+	if err := report.ResolveRefunds(transactions); err != nil {
+		t.Fatal(err)
+	}
+	if transactions[0].Category != "Clothing" {
+		t.Errorf("Expected the refund to net against Clothing, got %q", transactions[0].Category)
+	}
+	transactions = append(transactions, transaction.PayerTransaction{BasicTransaction: transaction.BasicTransaction{Time: "2025-03-29", Amount: currency.NewEuro(30), Description: "Rebate", RefundOf: "tx-4"}})
+	if err := report.ResolveRefunds(transactions); err == nil || !strings.Contains(err.Error(), `"tx-4" is neither the ID of a transaction nor a category`) {
+		t.Errorf("Expected an error for the refund of tx-4, got %v", err)
+	}
+	if transactions[2].Category != "" {
+		t.Errorf("Expected the refund of tx-4 not to be filed under a category, got %q", transactions[2].Category)
+	}
+}
+
+func TestReimbursementsAreSortedByDate(t *testing.T) {
+	transactions := []transaction.BasicTransaction{
+		{Time: "2025-03-10", Amount: currency.NewEuro(-300), Description: "Conference ticket", Category: "Travel", Reimbursable: true},
+		{Time: "05.02.2025", Amount: currency.NewEuro(-150), Description: "Hotel", Category: "Travel", Reimbursable: true},
+		{Time: "2025-03-28", Amount: currency.NewEuro(150), Description: "Expense claim", RefundOf: "Travel"},
+	}
+	var actual []string
+	for _, reimbursement := range report.Reimbursements(transactions) {
+		actual = append(actual, reimbursement.String())
+	}
+	expected := []string{
+		"05.02.2025,€-150.00,Hotel: €150.00 reimbursed, €0.00 outstanding",
+		"2025-03-10,€-300.00,Conference ticket: €0.00 reimbursed, €300.00 outstanding",
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Expected the oldest expense to be reimbursed first, got %s", diff)
+	}
+}
@@ -1,15 +1,12 @@
 package report
 
 import (
-	"encoding/csv"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"io"
 	"iter"
 	"os"
 	"slices"
-	"strconv"
-	"strings"
 
 	"github.com/kevslinger/budget/currency"
 	"github.com/kevslinger/budget/transaction"
@@ -19,6 +16,32 @@ type Report interface {
 	fmt.Stringer
 	Save(filename string) error
 	WriteCSV(writer io.Writer) error
+	WriteJSON(writer io.Writer) error
+}
+
+// jsonReport is the JSON encoding of a report
+type jsonReport[T any] struct {
+	Name          string        `json:"name"`
+	TotalIncome   currency.Euro `json:"totalIncome"`
+	TotalExpense  currency.Euro `json:"totalExpense"`
+	NetIncome     currency.Euro `json:"netIncome"`
+	TotalInvested currency.Euro `json:"totalInvested"`
+	Metrics       *Metrics      `json:"metrics,omitempty"`
+	Transactions  []T           `json:"transactions"`
+	Anomalies     []Anomaly     `json:"anomalies"`
+}
+
+// writeJSON writes the report as indented JSON, with empty lists rather than null
+func writeJSON[T any](writer io.Writer, report jsonReport[T]) error {
+	if report.Transactions == nil {
+		report.Transactions = []T{}
+	}
+	if report.Anomalies == nil {
+		report.Anomalies = []Anomaly{}
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
 }
 
 // CombineReports merges a slice of reports into a single report
@@ -33,44 +56,71 @@ func CombineReports(reportName string, reports []Report) (Report, error) {
 	return BasicReport{}, fmt.Errorf("unknown report type: %T", reports[0])
 }
 
-// ReadBudgetReportFromFile reads in a CSV file with transactions, and parses them to create a report
-func ReadBudgetReportFromFile(reportName string, path string) (Report, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return BasicReport{}, fmt.Errorf("error opening budget report file: %w", err)
+// CombineReportsWithOptions merges a slice of reports into a single report, dropping transactions which appear in
+// more than one report according to opts. It returns the duplicates which were dropped
+// It assumes that all reports are of the same type
+func CombineReportsWithOptions(reportName string, reports []Report, opts DedupOptions) (Report, []Duplicate, error) {
+	if len(reports) == 0 {
+		return BasicReport{}, nil, fmt.Errorf("no reports to combine")
 	}
-	reader := csv.NewReader(file)
-	line, err := reader.Read()
-	if err != nil {
-		return BasicReport{}, fmt.Errorf("error reading budet report file: %w", err)
+	switch reports[0].(type) {
+	case BasicReport:
+		return CombineBasicReportsWithOptions(reportName, reports, opts)
+	case MultiPayerReport:
+		return CombineMultiPayerReportsWithOptions(reportName, reports, opts)
 	}
-	file.Close()
-	switch len(line) {
-	case 3:
-		return ReadDefaultBudgetReportFromFile(reportName, path)
-	case 4:
-		return ReadMultiPayerBudgetReportFromFile(reportName, path)
+	return BasicReport{}, nil, fmt.Errorf("unknown report type: %T", reports[0])
+}
+
+// WriteTransactionsCSV writes the transactions to a CSV in the order given, including those with a zero amount, so
+// that a report file can be rewritten without reordering or dropping its rows. The Name column is written if hasPayer
+func WriteTransactionsCSV(writer io.Writer, transactions []transaction.PayerTransaction, hasPayer bool) error {
+	columns := usedColumns(basicTransactions(transactions))
+	header := "Time,Amount,Description"
+	if hasPayer {
+		header += ",Name"
 	}
-	var transactions []transaction.BasicTransaction
-	for err == nil {
-		time, amount, description := line[0], line[1], line[2]
-		// skip header
-		if strings.ToLower(time) == "time" && strings.ToLower(amount) == "amount" && strings.ToLower(description) == "description" {
-			line, err = reader.Read()
-			continue
-		}
-		amountFloat, parseErr := strconv.ParseFloat(amount, 64)
-		if parseErr != nil {
-			return BasicReport{}, fmt.Errorf("error parsing a transaction: %w", parseErr)
+	fmt.Fprint(writer, header+columnHeader(columns))
+	for _, tx := range transactions {
+		fmt.Fprintf(writer, "\n%s,%.2f,%s", tx.Time, float64(tx.Amount.Cents())/100, csvField(tx.Description))
+		if hasPayer {
+			fmt.Fprint(writer, ","+csvField(tx.PaidBy))
 		}
-		transactions = append(transactions, transaction.BasicTransaction{Time: time, Amount: currency.NewEuro(amountFloat), Description: description})
-		line, err = reader.Read()
+		fmt.Fprint(writer, columnValues(columns, tx.BasicTransaction))
+	}
+	return nil
+}
+
+// SaveTransactions saves the transactions to a CSV file in the order given, as WriteTransactionsCSV does
+func SaveTransactions(filename string, transactions []transaction.PayerTransaction, hasPayer bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WriteTransactionsCSV(file, transactions, hasPayer); err != nil {
+		return err
 	}
-	if !errors.Is(err, io.EOF) {
-		return BasicReport{}, fmt.Errorf("error reading currency report file: %w", err)
+	return file.Sync()
+}
+
+// ReadBudgetReportFromFile reads in a CSV file with transactions, and parses them to create a report
+// Files with a column saying who earned/paid each transaction produce a MultiPayerReport, and other files a BasicReport
+func ReadBudgetReportFromFile(reportName string, path string) (Report, error) {
+	transactions, hasPayer, err := ReadTransactionsFromFile(path)
+	if err != nil {
+		return BasicReport{}, err
 	}
+	return NewBudgetReport(reportName, transactions, hasPayer), nil
+}
 
-	return NewBasicBudgetReport(reportName, transactions), nil
+// NewBudgetReport creates a MultiPayerReport from the transactions if multiPayer is set, and otherwise a BasicReport
+// which ignores who earned/paid each transaction
+func NewBudgetReport(reportName string, transactions []transaction.PayerTransaction, multiPayer bool) Report {
+	if multiPayer {
+		return NewMultiPayerBudgetReport(reportName, transactions)
+	}
+	return NewBasicBudgetReport(reportName, basicTransactions(transactions))
 }
 
 func sortKeys(m iter.Seq[string]) []string {
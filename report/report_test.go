@@ -2,6 +2,8 @@ package report_test
 
 import (
 	"bytes"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -167,3 +169,264 @@ func TestSave(t *testing.T) {
 		t.Errorf("Expected %s, got %s, %s", expected, buffer.String(), cmp.Diff(expected, buffer.String()))
 	}
 }
+
+func TestReadBudgetReportFromFileDetectsPayerColumn(t *testing.T) {
+	actual, err := report.ReadBudgetReportFromFile("Test", "../testdata/multipayerreport.csv")
+	if err != nil {
+		t.Fatalf("Error while reading report from file: %v", err)
+	}
+	multiPayerReport, ok := actual.(report.MultiPayerReport)
+	if !ok {
+		t.Fatalf("Expected multi-payer report, got %T", actual)
+	}
+	expected := map[string]currency.Euro{"Joe": currency.NewEuro(300), "Charles": currency.NewEuro(75)}
+	for name, net := range expected {
+		if multiPayerReport.NetIncomePerPayer[name].Cmp(net) != 0 {
+			t.Errorf("Expected net income %s for %s, got %s", net.String(), name, multiPayerReport.NetIncomePerPayer[name].String())
+		}
+	}
+}
+
+func TestCalculateTotalExpensePerDescriptionAtDepth(t *testing.T) {
+	txs := []transaction.BasicTransaction{{Time: "1", Amount: currency.NewEuro(500.0), Description: "Income"}, {Time: "2", Amount: currency.NewEuro(-60.0), Description: "Food:Groceries"}, {Time: "3", Amount: currency.NewEuro(-40.0), Description: "Food:Restaurants"}, {Time: "4", Amount: currency.NewEuro(-100.0), Description: "Rent"}}
+	report := report.NewBasicBudgetReport("Test", txs)
+	tests := []struct {
+		depth    int
+		expected map[string]currency.Euro
+	}{
+		{0, map[string]currency.Euro{"Food:Groceries": currency.NewEuro(-60.0), "Food:Restaurants": currency.NewEuro(-40.0), "Rent": currency.NewEuro(-100.0)}},
+		{1, map[string]currency.Euro{"Food": currency.NewEuro(-100.0), "Rent": currency.NewEuro(-100.0)}},
+		{2, map[string]currency.Euro{"Food:Groceries": currency.NewEuro(-60.0), "Food:Restaurants": currency.NewEuro(-40.0), "Rent": currency.NewEuro(-100.0)}},
+	}
+	for _, test := range tests {
+		actual := report.CalculateTotalExpensePerDescriptionAtDepth(test.depth)
+		if len(actual) != len(test.expected) {
+			t.Fatalf("Expected %d expense categories at depth %d, got %v", len(test.expected), test.depth, actual)
+		}
+		for key, val := range test.expected {
+			if actual[key].Cmp(val) != 0 {
+				t.Errorf("Expected %s for %s at depth %d, got %s", val.String(), key, test.depth, actual[key].String())
+			}
+		}
+	}
+}
+
+func TestStringShowsCategoryTree(t *testing.T) {
+	txs := []transaction.BasicTransaction{{Time: "2", Amount: currency.NewEuro(-60.0), Description: "Food:Groceries"}, {Time: "3", Amount: currency.NewEuro(-40.0), Description: "Food:Restaurants"}, {Time: "4", Amount: currency.NewEuro(-100.0), Description: "Rent"}}
+	expected := `Food: €-100.00 (50.00%)
+  Groceries: €-60.00 (60.00% of Food, 30.00% of total)
+  Restaurants: €-40.00 (40.00% of Food, 20.00% of total)
+Rent: €-100.00 (50.00%)
+`
+	actual := report.NewBasicBudgetReport("Test", txs).String()
+	if !strings.Contains(actual, expected) {
+		t.Errorf("Expected the report to contain %s, got %s", expected, actual)
+	}
+}
+
+func TestStringWithoutExpensesShowsZeroPercent(t *testing.T) {
+	txs := []transaction.BasicTransaction{{Time: "2", Amount: currency.NewEuro(-50.0), Description: "Shoes", ID: "1", Category: "Clothing:Shoes"}, {Time: "3", Amount: currency.NewEuro(50.0), Description: "Shoes refund", RefundOf: "1", Category: "Clothing:Shoes"}}
+	expected := `Clothing: €0.00 (0.00%)
+  Shoes: €0.00 (0.00% of Clothing, 0.00% of total)
+`
+	actual := report.NewBasicBudgetReport("Test", txs).String()
+	if !strings.Contains(actual, expected) || strings.Contains(actual, "NaN") {
+		t.Errorf("Expected the report to contain %s, got %s", expected, actual)
+	}
+}
+
+func TestReadAndWriteOptionalColumns(t *testing.T) {
+	actual, err := report.ReadDefaultBudgetReportFromFile("Test", "../testdata/categorisedreport.csv")
+	if err != nil {
+		t.Fatalf("Error while reading report from file: %v", err)
+	}
+	dinner := actual.SortExpenses()[0]
+	if dinner.Category != "Food:Restaurants" || dinner.Payee != "Pizzeria Napoli" || dinner.Notes != "Birthday dinner, with Anna" || !slices.Equal(dinner.Tags, []string{"vacation-2025", "birthday"}) {
+		t.Errorf("Expected the optional columns to be read, got %#v", dinner)
+	}
+	expected := `Time,Amount,Description,Category,Payee,Notes,Tags
+2025-01-31,2500.00,Salary January,Income,ACME GmbH,,
+2025-01-03,-40.00,Pizzeria Napoli,Food:Restaurants,Pizzeria Napoli,"Birthday dinner, with Anna",vacation-2025;birthday
+2025-01-02,-25.50,REWE SAGT DANKE 1234,Food:Groceries,REWE,,
+2025-01-09,-12.00,REWE SAGT DANKE 5678,Household,REWE,,`
+	buffer := new(bytes.Buffer)
+	if err := actual.WriteCSV(buffer); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != expected {
+		t.Errorf("Expected %s, got %s, %s", expected, buffer.String(), cmp.Diff(expected, buffer.String()))
+	}
+}
+
+func TestCalculateTotalExpensePer(t *testing.T) {
+	actual, err := report.ReadDefaultBudgetReportFromFile("Test", "../testdata/categorisedreport.csv")
+	if err != nil {
+		t.Fatalf("Error while reading report from file: %v", err)
+	}
+	tests := []struct {
+		dimension report.Dimension
+		expected  map[string]currency.Euro
+	}{
+		{report.ByCategory, map[string]currency.Euro{"Food:Groceries": currency.NewEuro(-25.5), "Food:Restaurants": currency.NewEuro(-40), "Household": currency.NewEuro(-12)}},
+		{report.ByPayee, map[string]currency.Euro{"REWE": currency.NewEuro(-37.5), "Pizzeria Napoli": currency.NewEuro(-40)}},
+		{report.ByDescription, map[string]currency.Euro{"REWE SAGT DANKE 1234": currency.NewEuro(-25.5), "Pizzeria Napoli": currency.NewEuro(-40), "REWE SAGT DANKE 5678": currency.NewEuro(-12)}},
+	}
+	for _, test := range tests {
+		totals := actual.CalculateTotalExpensePer(test.dimension)
+		if len(totals) != len(test.expected) {
+			t.Errorf("Expected %d totals for dimension %d, got %v", len(test.expected), test.dimension, totals)
+		}
+		for key, val := range test.expected {
+			if totals[key].Cmp(val) != 0 {
+				t.Errorf("Expected %s for %s, got %s", val.String(), key, totals[key].String())
+			}
+		}
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	transactions, _, err := report.ReadTransactionsFromFile("../testdata/categorisedreport.csv")
+	if err != nil {
+		t.Fatalf("Error while reading transactions from file: %v", err)
+	}
+	tests := []struct {
+		filter   report.TagFilter
+		expected int
+	}{
+		{report.TagFilter{}, 4},
+		{report.TagFilter{Include: []string{"Vacation-2025", "wedding"}}, 1},
+		{report.TagFilter{Include: []string{"vacation-2025", "wedding"}, MatchAll: true}, 0},
+		{report.TagFilter{Include: []string{"vacation-2025", "birthday"}, MatchAll: true}, 1},
+		{report.TagFilter{Exclude: []string{"birthday"}}, 3},
+	}
+	for _, test := range tests {
+		basicReport := report.NewBasicBudgetReportWithTagFilter("Test", basicTransactions(transactions), test.filter)
+		if len(basicReport.Transactions()) != test.expected {
+			t.Errorf("Expected %d transactions with filter %#v, got %d", test.expected, test.filter, len(basicReport.Transactions()))
+		}
+		multiPayerReport := report.NewMultiPayerBudgetReportWithTagFilter("Test", transactions, test.filter)
+		if len(multiPayerReport.Transactions()) != test.expected {
+			t.Errorf("Expected %d shared transactions with filter %#v, got %d", test.expected, test.filter, len(multiPayerReport.Transactions()))
+		}
+	}
+}
+
+func TestCalculateTotalExpensePerTag(t *testing.T) {
+	txs := []transaction.BasicTransaction{{Time: "1", Amount: currency.NewEuro(-100), Description: "Hotel", Tags: []string{"vacation-2025"}}, {Time: "2", Amount: currency.NewEuro(-40), Description: "Dinner", Tags: []string{"Vacation-2025", "birthday"}}, {Time: "3", Amount: currency.NewEuro(-10), Description: "Groceries"}, {Time: "4", Amount: currency.NewEuro(50), Description: "Refund", Tags: []string{"vacation-2025"}}, {Time: "5", Amount: currency.NewEuro(-5), Description: "Cake", Tags: []string{"Birthday", "birthday"}}}
+	basicReport := report.NewBasicBudgetReport("Test", txs)
+	expected := map[string]currency.Euro{"vacation-2025": currency.NewEuro(-140), "birthday": currency.NewEuro(-45)}
+	actual := basicReport.CalculateTotalExpensePerTag()
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d tags, got %v", len(expected), actual)
+	}
+	for tag, val := range expected {
+		if actual[tag].Cmp(val) != 0 {
+			t.Errorf("Expected %s for %s, got %s", val.String(), tag, actual[tag].String())
+		}
+	}
+	if !strings.Contains(basicReport.String(), "Total Expense Per Tag (% of total expenses)\nbirthday: €-45.00 (29.03%)\nvacation-2025: €-140.00 (90.32%)\n") {
+		t.Errorf("Expected the report to list the expenses per tag, got %s", basicReport.String())
+	}
+}
+
+func basicTransactions(transactions []transaction.PayerTransaction) []transaction.BasicTransaction {
+	var basic []transaction.BasicTransaction
+	for _, tx := range transactions {
+		basic = append(basic, tx.BasicTransaction)
+	}
+	return basic
+}
+
+func TestTransfersAreNotIncomeOrExpense(t *testing.T) {
+	checking, err := report.ReadDefaultBudgetReportFromFile("January", "../testdata/checking.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	savings, err := report.ReadDefaultBudgetReportFromFile("January", "../testdata/savings.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined, err := report.CombineReports("January", []report.Report{checking, savings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paired, pairs, unpaired := report.PairReportTransfers(combined, 3)
+	if len(pairs) != 1 || pairs[0].From.Account != "Checking" || pairs[0].To.Account != "Savings" {
+		t.Errorf("Expected one transfer from Checking to Savings, got %v", pairs)
+	}
+	if len(unpaired) != 1 || unpaired[0].Description != "Transfer to brokerage" {
+		t.Errorf("Expected the transfer to the brokerage to be unpaired, got %v", unpaired)
+	}
+	basicReport := paired.(report.BasicReport)
+	expectedIncome, expectedExpense := currency.NewEuro(3002.5), currency.NewEuro(-1000)
+	if basicReport.TotalIncome.Cmp(expectedIncome) != 0 || basicReport.TotalExpense.Cmp(expectedExpense) != 0 {
+		t.Errorf("Expected income %s and expense %s, got %s and %s", expectedIncome.String(), expectedExpense.String(), basicReport.TotalIncome.String(), basicReport.TotalExpense.String())
+	}
+	expectedBalances := map[string]currency.Euro{"Checking": currency.NewEuro(1300), "Savings": currency.NewEuro(502.5)}
+	actualBalances := basicReport.CalculateBalancePerAccount()
+	for account, balance := range expectedBalances {
+		if actualBalances[account].Cmp(balance) != 0 {
+			t.Errorf("Expected %s balance to change by %s, got %s", account, balance.String(), actualBalances[account].String())
+		}
+	}
+}
+
+func TestPairTransfersLeavesInvestmentsAlone(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-01-05", Amount: currency.NewEuro(-500), Description: "Transfer out", Account: "Checking", Kind: transaction.KindTransfer}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-01-05", Amount: currency.NewEuro(500), Description: "Fund sale", Account: "Brokerage", Kind: transaction.KindInvestment}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-01-06", Amount: currency.NewEuro(-200), Description: "Fund purchase", Account: "Brokerage", Kind: transaction.KindInvestment}},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2025-01-06", Amount: currency.NewEuro(200), Description: "Transfer in", Account: "Savings", Kind: transaction.KindTransfer}},
+	}
+	paired, pairs, unpaired := report.PairTransfers(transactions, 3)
+	if len(pairs) != 0 || len(unpaired) != 2 {
+		t.Errorf("Expected no transfer to pair with an investment, got pairs %v and unpaired %v", pairs, unpaired)
+	}
+	if paired[1].Kind != transaction.KindInvestment || paired[2].Kind != transaction.KindInvestment {
+		t.Errorf("Expected the investments to keep their kind, got %s and %s", paired[1].Kind, paired[2].Kind)
+	}
+}
+
+func TestMultiPayerReportTotalsIncomePerPayer(t *testing.T) {
+	transactions := []transaction.PayerTransaction{
+		{BasicTransaction: transaction.BasicTransaction{Time: "1", Amount: currency.NewEuro(500), Description: "Income"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "2", Amount: currency.NewEuro(-200), Description: "Rent"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "3", Amount: currency.NewEuro(100), Description: "Income"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "4", Amount: currency.NewEuro(0), Description: "Voided"}, PaidBy: "Joe"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "5", Amount: currency.NewEuro(-50), Description: "Groceries"}, PaidBy: "Charles"},
+		{BasicTransaction: transaction.BasicTransaction{Time: "6", Amount: currency.NewEuro(300), Description: "Income"}, PaidBy: "Charles"},
+	}
+	multiPayerReport := report.NewMultiPayerBudgetReport("Test", transactions)
+	euros := cmp.Comparer(func(a, b currency.Euro) bool { return a.Cmp(b) == 0 })
+	expectedIncome := map[string]currency.Euro{"Joe": currency.NewEuro(600), "Charles": currency.NewEuro(300)}
+	if diff := cmp.Diff(expectedIncome, multiPayerReport.TotalIncomePerPayer, euros); diff != "" {
+		t.Errorf("Expected the income per payer to leave out their expenses, got %s", diff)
+	}
+	expectedExpense := map[string]currency.Euro{"Joe": currency.NewEuro(-200), "Charles": currency.NewEuro(-50)}
+	if diff := cmp.Diff(expectedExpense, multiPayerReport.TotalExpensePerPayer, euros); diff != "" {
+		t.Errorf("Expected the expense per payer to leave out their incomes, got %s", diff)
+	}
+	expectedNet := map[string]currency.Euro{"Joe": currency.NewEuro(400), "Charles": currency.NewEuro(250)}
+	if diff := cmp.Diff(expectedNet, multiPayerReport.NetIncomePerPayer, euros); diff != "" {
+		t.Errorf("Expected the net income per payer to be their income plus their expense, got %s", diff)
+	}
+}
+
+func TestInvestmentsAreSavedNotSpent(t *testing.T) {
+	transactions := []transaction.BasicTransaction{
+		{Time: "2025-01-01", Amount: currency.NewEuro(2000), Description: "Salary"},
+		{Time: "2025-01-02", Amount: currency.NewEuro(-500), Description: "Brokerage deposit", Kind: transaction.KindInvestment},
+		{Time: "2025-01-05", Amount: currency.NewEuro(-300), Description: "Groceries"},
+	}
+	r := report.NewBasicBudgetReport("January", transactions)
+	if r.TotalExpense.Cmp(currency.NewEuro(-300)) != 0 || r.TotalInvested.Cmp(currency.NewEuro(500)) != 0 {
+		t.Errorf("Expected €-300.00 spent and €500.00 invested, got %s and %s", r.TotalExpense.String(), r.TotalInvested.String())
+	}
+	if !strings.Contains(r.String(), "Total Invested (saved, not spent): €500.00\n") {
+		t.Errorf("Expected the total invested in the report, got %s", r.String())
+	}
+	shared := report.NewMultiPayerBudgetReport("January", []transaction.PayerTransaction{{BasicTransaction: transactions[1], PaidBy: "Joe"}})
+	if shared.TotalExpense.Cents() != 0 || shared.TotalInvested.Cmp(currency.NewEuro(500)) != 0 {
+		t.Errorf("Expected the investment to not be spent, got %s spent and %s invested", shared.TotalExpense.String(), shared.TotalInvested.String())
+	}
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// resolveSplits fills in the time, description, account and payer of child lines which leave them blank from their
+// parent, and checks that the child lines of every split transaction add up to it
+func resolveSplits(transactions []transaction.PayerTransaction) []splitProblem {
+	parents := make(map[string]transaction.PayerTransaction)
+	for _, tx := range transactions {
+		if tx.ID != "" && tx.Parent == "" {
+			parents[tx.ID] = tx
+		}
+	}
+	for i, tx := range transactions {
+		parent, ok := parents[tx.Parent]
+		if tx.Parent == "" || !ok {
+			continue
+		}
+		if tx.Time == "" {
+			transactions[i].Time = parent.Time
+		}
+		if tx.Description == "" {
+			transactions[i].Description = parent.Description
+		}
+		if tx.Account == "" {
+			transactions[i].Account = parent.Account
+		}
+		if tx.PaidBy == "" {
+			transactions[i].PaidBy = parent.PaidBy
+		}
+	}
+	return splitProblems(basicTransactions(transactions))
+}
+
+// splitProblem is something wrong with a split transaction, found at the transaction with index idx: the child line
+// whose parent is missing or itself a child line, or the parent whose child lines do not add up
+type splitProblem struct {
+	idx int
+	// name is the column with the wrong value, either ID or Parent
+	name   string
+	value  string
+	reason string
+}
+
+// Error describes the problem
+func (p splitProblem) Error() string {
+	return fmt.Sprintf("error splitting a transaction: %s %q: %s", p.name, p.value, p.reason)
+}
+
+// ValidateSplits checks that the child lines of every split transaction add up to the parent's amount, and that
+// every child line's parent exists and is not itself a child line
+func ValidateSplits(transactions []transaction.BasicTransaction) error {
+	if problems := splitProblems(transactions); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// splitProblems returns every problem of the split transactions: first those of the child lines, in order, then those
+// of the parents, sorted by ID
+func splitProblems(transactions []transaction.BasicTransaction) []splitProblem {
+	parents := make(map[string]int)
+	for idx, tx := range transactions {
+		if tx.ID != "" {
+			parents[tx.ID] = idx
+		}
+	}
+	var problems []splitProblem
+	sums := make(map[string]currency.Euro)
+	for idx, tx := range transactions {
+		if tx.Parent == "" {
+			continue
+		}
+		parent, ok := parents[tx.Parent]
+		if !ok {
+			problems = append(problems, splitProblem{idx: idx, name: "Parent", value: tx.Parent, reason: "no parent transaction with this ID"})
+			continue
+		}
+		if transactions[parent].Parent != "" {
+			problems = append(problems, splitProblem{idx: idx, name: "Parent", value: tx.Parent, reason: "the parent transaction is itself a child line"})
+			continue
+		}
+		sums[tx.Parent] = currency.AddEuros(sums[tx.Parent], tx.Amount)
+	}
+	for _, id := range sortKeys(maps.Keys(sums)) {
+		parent := transactions[parents[id]]
+		if sums[id].Cmp(parent.Amount) != 0 {
+			problems = append(problems, splitProblem{idx: parents[id], name: "ID", value: id, reason: fmt.Sprintf("the child lines add up to %s, not %s", sums[id].String(), parent.Amount.String())})
+		}
+	}
+	return problems
+}
+
+// splitLines replaces every split transaction with its child lines, so that amounts are counted once, in the
+// categories and for the payers of the child lines
+func splitLines[T any](transactions []T, basic func(T) transaction.BasicTransaction) []T {
+	split := make(map[string]bool)
+	for _, tx := range transactions {
+		if parent := basic(tx).Parent; parent != "" {
+			split[parent] = true
+		}
+	}
+	if len(split) == 0 {
+		return transactions
+	}
+	lines := make([]T, 0, len(transactions))
+	for _, tx := range transactions {
+		if b := basic(tx); b.Parent == "" && split[b.ID] {
+			continue
+		}
+		lines = append(lines, tx)
+	}
+	return lines
+}
+
+// SplitLines replaces every split transaction with its child lines, so that amounts are counted once. The child lines
+// no longer reference their parent, so they stand on their own when saved
+func SplitLines(transactions []transaction.BasicTransaction) []transaction.BasicTransaction {
+	lines := slices.Clone(splitLines(transactions, func(tx transaction.BasicTransaction) transaction.BasicTransaction { return tx }))
+	for i := range lines {
+		lines[i].Parent = ""
+	}
+	return lines
+}
+
+// splitPayerLines replaces every split transaction with its child lines, keeping who earned/paid each line
+func splitPayerLines(transactions []transaction.PayerTransaction) []transaction.PayerTransaction {
+	return splitLines(transactions, func(tx transaction.PayerTransaction) transaction.BasicTransaction { return tx.BasicTransaction })
+}
+
+// SplitPayerLines is SplitLines for transactions which were earned/paid by someone, keeping who earned/paid each line
+func SplitPayerLines(transactions []transaction.PayerTransaction) []transaction.PayerTransaction {
+	lines := slices.Clone(splitPayerLines(transactions))
+	for i := range lines {
+		lines[i].Parent = ""
+	}
+	return lines
+}
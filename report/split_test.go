@@ -0,0 +1,61 @@
+package report_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func TestSplitTransactionsAggregateTheirChildLines(t *testing.T) {
+	r, err := report.ReadMultiPayerBudgetReportFromFile("split", "../testdata/splitreport.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TotalExpense.Cmp(currency.NewEuro(-80)) != 0 {
+		t.Errorf("Expected the split transaction to be counted once, got a total expense of %s", r.TotalExpense)
+	}
+	expected := map[string]currency.Euro{"Food:Groceries": currency.NewEuro(-65), "Household": currency.NewEuro(-15)}
+	if diff := cmp.Diff(expected, r.CalculateTotalExpensePerDescription(), cmp.Comparer(func(a, b currency.Euro) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("Expected the expenses of the child lines' categories, got %s", diff)
+	}
+	expectedPerPayer := map[string]currency.Euro{"Joe": currency.NewEuro(-45), "Charles": currency.NewEuro(-35)}
+	if diff := cmp.Diff(expectedPerPayer, r.TotalExpensePerPayer, cmp.Comparer(func(a, b currency.Euro) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("Expected the child lines' payers, got %s", diff)
+	}
+	for _, tx := range r.Transactions() {
+		if tx.Parent == "tx-2" && (tx.Time != "2025-03-03" || tx.Description != "SUPERMARKET 123") {
+			t.Errorf("Expected the child line to inherit its parent's time and description, got %+v", tx)
+		}
+	}
+}
+
+func TestSplitTransactionsMustAddUp(t *testing.T) {
+	_, _, err := report.ReadTransactionsFromFile("../testdata/badsplitreport.csv")
+	if err == nil || !strings.Contains(err.Error(), "add up to €-55.00, not €-60.00") {
+		t.Errorf("Expected an error about the child lines not adding up, got %v", err)
+	}
+	orphan := []transaction.BasicTransaction{{Amount: currency.NewEuro(-10), Parent: "missing"}}
+	if err := report.ValidateSplits(orphan); err == nil {
+		t.Error("Expected an error about the missing parent")
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	transactions := []transaction.BasicTransaction{
+		{Amount: currency.NewEuro(-60), ID: "tx-2"},
+		{Amount: currency.NewEuro(-45), Category: "Food", Parent: "tx-2"},
+		{Amount: currency.NewEuro(-15), Category: "Household", Parent: "tx-2"},
+		{Amount: currency.NewEuro(-20), ID: "tx-3"},
+	}
+	lines := report.SplitLines(transactions)
+	if len(lines) != 3 || lines[0].Category != "Food" || lines[0].Parent != "" || lines[2].ID != "tx-3" {
+		t.Errorf("Expected the parent to be replaced by its child lines, got %+v", lines)
+	}
+	if transactions[1].Parent != "tx-2" {
+		t.Error("Expected the original transactions to be left unchanged")
+	}
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// TagFilter selects transactions by their tags. Tags are compared ignoring case
+type TagFilter struct {
+	// Include keeps only transactions with any of the tags, or all of them if MatchAll is set. An empty Include keeps
+	// every transaction
+	Include  []string
+	MatchAll bool
+	// Exclude drops transactions with any of the tags
+	Exclude []string
+}
+
+// Matches reports whether the filter keeps the transaction
+func (f TagFilter) Matches(tx transaction.BasicTransaction) bool {
+	hasTag := func(tag string) bool {
+		return slices.ContainsFunc(tx.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	}
+	if slices.ContainsFunc(f.Exclude, hasTag) {
+		return false
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	if f.MatchAll {
+		return !slices.ContainsFunc(f.Include, func(tag string) bool { return !hasTag(tag) })
+	}
+	return slices.ContainsFunc(f.Include, hasTag)
+}
+
+// NewBasicBudgetReportWithTagFilter creates a new report from only the transactions kept by the filter
+func NewBasicBudgetReportWithTagFilter(reportName string, transactions []transaction.BasicTransaction, filter TagFilter) BasicReport {
+	var filtered []transaction.BasicTransaction
+	for _, tx := range transactions {
+		if filter.Matches(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return NewBasicBudgetReport(reportName, filtered)
+}
+
+// NewMultiPayerBudgetReportWithTagFilter creates a new shared report from only the transactions kept by the filter
+func NewMultiPayerBudgetReportWithTagFilter(reportName string, transactions []transaction.PayerTransaction, filter TagFilter) MultiPayerReport {
+	var filtered []transaction.PayerTransaction
+	for _, tx := range transactions {
+		if filter.Matches(tx.BasicTransaction) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return NewMultiPayerBudgetReport(reportName, filtered)
+}
+
+// totalExpensePerTag aggregates the expenses by lower-cased tag, as tags are compared ignoring case. Expenses with
+// several tags count towards each of them, and untagged expenses are left out
+func totalExpensePerTag(transactions []transaction.BasicTransaction) map[string]currency.Euro {
+	expensePerTag := make(map[string]currency.Euro)
+	for _, transaction := range transactions {
+		if transaction.IsExpense() {
+			tags := make(map[string]bool)
+			for _, tag := range transaction.Tags {
+				tags[strings.ToLower(tag)] = true
+			}
+			for tag := range tags {
+				expensePerTag[tag] = currency.AddEuros(expensePerTag[tag], transaction.Amount)
+			}
+		}
+	}
+	return expensePerTag
+}
+
+// writeExpensePerTag writes the expenses per tag, if any expenses are tagged. As expenses can have several tags,
+// the shares of the total expense can add up to more than 100%
+func writeExpensePerTag(str *strings.Builder, expensePerTag map[string]currency.Euro, totalExpense currency.Euro) {
+	if len(expensePerTag) == 0 {
+		return
+	}
+	str.WriteString("Total Expense Per Tag (% of total expenses)\n")
+	for _, tag := range sortKeys(maps.Keys(expensePerTag)) {
+		str.WriteString(fmt.Sprintf("%s: %s (%.2f%%)\n", tag, expensePerTag[tag].String(), percentage(expensePerTag[tag], totalExpense)))
+	}
+}
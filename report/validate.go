@@ -0,0 +1,146 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Problem is something wrong with a row of a report file
+type Problem struct {
+	File string
+	Line int
+	// Column is the column of the value, counting from 1, or zero for problems with the whole row or file
+	Column int
+	// Name is the name of the column
+	Name   string
+	Value  string
+	Reason string
+	// Fatal problems make a row unusable, so it is skipped in lenient mode
+	Fatal bool
+}
+
+// Error describes where the problem is, the value and what is wrong with it, e.g.
+// report.csv:3:2: Amount "12,50": not a number
+func (p Problem) Error() string {
+	var str strings.Builder
+	str.WriteString(p.File)
+	if p.Line > 0 {
+		str.WriteString(fmt.Sprintf(":%d", p.Line))
+		if p.Column > 0 {
+			str.WriteString(fmt.Sprintf(":%d", p.Column))
+		}
+	}
+	str.WriteString(": ")
+	if p.Name != "" {
+		str.WriteString(p.Name)
+		if p.Value != "" {
+			str.WriteString(fmt.Sprintf(" %q", p.Value))
+		}
+		str.WriteString(": ")
+	}
+	return str.String() + p.Reason
+}
+
+// ValidationMode says what to do with the problems found while validating a report file
+type ValidationMode int
+
+const (
+	// Lenient skips the rows with fatal problems and keeps the others
+	Lenient ValidationMode = iota
+	// Strict fails if the file has any problem
+	Strict
+)
+
+// Validation is the result of validating a report file
+type Validation struct {
+	// Transactions are the rows without fatal problems
+	Transactions []transaction.PayerTransaction
+	HasPayer     bool
+	Rows         int
+	Problems     []Problem
+}
+
+// Skipped returns the number of rows which were skipped because of a fatal problem
+func (v Validation) Skipped() int {
+	return v.Rows - len(v.Transactions)
+}
+
+// ValidateTransactionsFile reads a CSV report file, collecting every problem with its rows: unreadable amounts and
+// column values, unrecognised dates, missing times, amounts and descriptions, rows with the wrong number of columns,
+// blank categories, split transactions whose child lines do not add up or whose parent is missing, and refunds of
+// unknown transactions or categories. Split transactions with a problem are skipped together with their child lines,
+// as are the child lines of skipped rows. In strict mode, it returns an error if there are any problems
+func ValidateTransactionsFile(path string, mode ValidationMode) (Validation, error) {
+	rows, layout, err := readRows(path)
+	if err != nil {
+		return Validation{}, err
+	}
+	validation := Validation{HasPayer: layout.payer >= 0, Rows: len(rows)}
+	parents := make(map[string]bool)
+	for _, row := range rows {
+		if row.tx.Parent != "" {
+			parents[row.tx.Parent] = true
+		}
+	}
+	category, parent, refundOf := layout.index("Category"), layout.index("Parent"), layout.index("Refund Of")
+	// skipped are the IDs of the split transactions which were skipped, and lines the lines of the transactions
+	skipped := make(map[string]bool)
+	var lines []int
+	for _, row := range rows {
+		validation.Problems = append(validation.Problems, row.problems...)
+		if slices.ContainsFunc(row.problems, func(p Problem) bool { return p.Fatal }) {
+			if row.tx.ID != "" && row.tx.Parent == "" {
+				skipped[row.tx.ID] = true
+			}
+			continue
+		}
+		if category >= 0 && row.tx.Category == "" && row.tx.IsIncomeOrExpense() && row.tx.RefundOf == "" && !parents[row.tx.ID] {
+			validation.Problems = append(validation.Problems, Problem{File: path, Line: row.line, Column: category + 1, Name: "Category", Reason: "blank category"})
+		}
+		validation.Transactions = append(validation.Transactions, row.tx)
+		lines = append(lines, row.line)
+	}
+	// keep keeps the transactions which are not dropped, with their lines
+	keep := func(dropped func(idx int, tx transaction.PayerTransaction) bool) {
+		var transactions []transaction.PayerTransaction
+		var kept []int
+		for idx, tx := range validation.Transactions {
+			if !dropped(idx, tx) {
+				transactions = append(transactions, tx)
+				kept = append(kept, lines[idx])
+			}
+		}
+		validation.Transactions, lines = transactions, kept
+	}
+	// childOfSkipped skips the child lines of the split transactions which were skipped
+	childOfSkipped := func(idx int, tx transaction.PayerTransaction) bool {
+		if !skipped[tx.Parent] {
+			return false
+		}
+		validation.Problems = append(validation.Problems, Problem{File: path, Line: lines[idx], Column: parent + 1, Name: "Parent", Value: tx.Parent, Reason: "the parent transaction was skipped", Fatal: true})
+		return true
+	}
+	keep(childOfSkipped)
+	drop := make(map[int]bool)
+	for _, p := range resolveSplits(validation.Transactions) {
+		column := parent
+		if p.name == "ID" {
+			column = layout.index("ID")
+			skipped[p.value] = true
+		}
+		validation.Problems = append(validation.Problems, Problem{File: path, Line: lines[p.idx], Column: column + 1, Name: p.name, Value: p.value, Reason: p.reason, Fatal: true})
+		drop[p.idx] = true
+	}
+	keep(func(idx int, tx transaction.PayerTransaction) bool { return drop[idx] })
+	keep(childOfSkipped)
+	for _, idx := range resolveRefunds(validation.Transactions) {
+		validation.Problems = append(validation.Problems, Problem{File: path, Line: lines[idx], Column: refundOf + 1, Name: "Refund Of", Value: validation.Transactions[idx].RefundOf, Reason: "neither the ID of a transaction nor a category in the file"})
+	}
+	if mode == Strict && len(validation.Problems) > 0 {
+		return validation, fmt.Errorf("%s has %d problem(s), the first being %w", path, len(validation.Problems), validation.Problems[0])
+	}
+	return validation, nil
+}
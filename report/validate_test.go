@@ -0,0 +1,139 @@
+package report_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kevslinger/budget/report"
+)
+
+func TestValidateTransactionsFileCollectsEveryProblem(t *testing.T) {
+	validation, err := report.ValidateTransactionsFile("../testdata/invalidreport.csv", report.Lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, problem := range validation.Problems {
+		actual = append(actual, problem.Error())
+	}
+	expected := []string{
+		`../testdata/invalidreport.csv:3:2: Amount "12;50": not a number`,
+		`../testdata/invalidreport.csv:4:1: Time "2025-03-32": unrecognised date`,
+		`../testdata/invalidreport.csv:5:3: Description: missing description`,
+		`../testdata/invalidreport.csv:5:4: Category: blank category`,
+		`../testdata/invalidreport.csv:6: expected 5 columns, got 4`,
+		`../testdata/invalidreport.csv:7:2: Amount: missing amount`,
+		`../testdata/invalidreport.csv:9:5: Kind "gift": unknown transaction kind "gift"`,
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Expected every problem with its location, got %s", diff)
+	}
+	if validation.Rows != 8 || validation.Skipped() != 4 {
+		t.Errorf("Expected 4 of 8 rows to be skipped, got %d of %d", validation.Skipped(), validation.Rows)
+	}
+}
+
+func TestValidateTransactionsFileStrict(t *testing.T) {
+	if _, err := report.ValidateTransactionsFile("../testdata/invalidreport.csv", report.Strict); err == nil || !strings.Contains(err.Error(), "has 7 problem(s)") {
+		t.Errorf("Expected strict validation to fail, got %v", err)
+	}
+	if _, err := report.ValidateTransactionsFile("../testdata/defaultreport.csv", report.Lenient); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestReadTransactionsFromFileLocatesTheFirstProblem(t *testing.T) {
+	_, _, err := report.ReadTransactionsFromFile("../testdata/invalidreport.csv")
+	if err == nil || err.Error() != `error parsing a transaction: ../testdata/invalidreport.csv:3:2: Amount "12;50": not a number` {
+		t.Errorf("Expected the location of the bad amount, got %v", err)
+	}
+}
+
+func TestValidateTransactionsFileRejectsUnknownYesNoValues(t *testing.T) {
+	for _, name := range []string{"Generated", "Reimbursable", "Cleared"} {
+		path := filepath.Join(t.TempDir(), "report.csv")
+		contents := "Time,Amount,Description," + name + "\n2025-03-01,-10,Coffee,Y\n2025-03-02,-20,Lunch,maybe\n"
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		validation, err := report.ValidateTransactionsFile(path, report.Lenient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		for _, problem := range validation.Problems {
+			actual = append(actual, problem.Error())
+		}
+		expected := []string{path + `:3:4: ` + name + ` "maybe": expected yes or no`}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Errorf("Expected only the unknown %s value to be a problem, got %s", name, diff)
+		}
+	}
+}
+
+func TestValidateTransactionsFileReportsUnknownRefunds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	contents := "Time,Amount,Description,ID,Category,Refund Of\n2025-03-01,-80,Shoes,tx-1,Clothing,\n2025-03-05,80,Shoes returned,tx-2,,tx-1\n2025-03-06,20,Partial refund,tx-3,,Clothing\n2025-03-07,30,Rebate,tx-4,,tx-9\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validation, err := report.ValidateTransactionsFile(path, report.Lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, problem := range validation.Problems {
+		actual = append(actual, problem.Error())
+	}
+	expected := []string{path + `:5:6: Refund Of "tx-9": neither the ID of a transaction nor a category in the file`}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Expected only the refund of tx-9 to be a problem, got %s", diff)
+	}
+	if category := validation.Transactions[3].Category; category != "" {
+		t.Errorf("Expected the refund of tx-9 to be left uncategorised, got %q", category)
+	}
+}
+
+func TestValidateTransactionsFileSkipsBrokenSplits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	contents := "Time,Amount,Description,ID,Category,Parent\n" +
+		"2025-03-01,-60,Supermarket,tx-1,,\n" +
+		",-45,,,Food,tx-1\n" +
+		",-10,,,Household,tx-1\n" +
+		"2025-03-02,abc,Pharmacy,tx-2,,\n" +
+		",-5,,,Health,tx-2\n" +
+		"2025-03-03,-30,Hardware,tx-3,,\n" +
+		",-20,,,Household,tx-3\n" +
+		",-5,,,Food,tx-9\n" +
+		"2025-03-04,-12,Bakery,tx-4,Food,\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	validation, err := report.ValidateTransactionsFile(path, report.Lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, problem := range validation.Problems {
+		actual = append(actual, problem.Error())
+	}
+	expected := []string{
+		path + `:5:2: Amount "abc": not a number`,
+		path + `:6:6: Parent "tx-2": the parent transaction was skipped`,
+		path + `:9:6: Parent "tx-9": no parent transaction with this ID`,
+		path + `:2:4: ID "tx-1": the child lines add up to €-55.00, not €-60.00`,
+		path + `:7:4: ID "tx-3": the child lines add up to €-20.00, not €-30.00`,
+		path + `:3:6: Parent "tx-1": the parent transaction was skipped`,
+		path + `:4:6: Parent "tx-1": the parent transaction was skipped`,
+		path + `:8:6: Parent "tx-3": the parent transaction was skipped`,
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Expected every split problem at its line, got %s", diff)
+	}
+	if len(validation.Transactions) != 1 || validation.Transactions[0].Description != "Bakery" {
+		t.Errorf("Expected only the bakery to be kept, got %v", validation.Transactions)
+	}
+}
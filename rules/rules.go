@@ -0,0 +1,195 @@
+package rules
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Rule assigns a category to the transactions it matches. Criteria which are left empty match every transaction
+type Rule struct {
+	// Line is the line of the rules file which the rule was read from, and is used to identify the rule
+	Line int
+	// Priority decides between several matching rules, with the highest priority winning
+	Priority int
+	Category string
+	// Contains matches descriptions containing the text, ignoring case
+	Contains string
+	// Pattern matches descriptions against a regular expression
+	Pattern *regexp.Regexp
+	// MinAmount and MaxAmount bound the signed amount, so expenses are matched with negative values
+	MinAmount *currency.Euro
+	MaxAmount *currency.Euro
+	PaidBy    string
+	// From and To bound the date of the transaction, inclusively
+	From time.Time
+	To   time.Time
+}
+
+// Matches reports whether the transaction meets every criteria of the rule
+func (r Rule) Matches(tx transaction.PayerTransaction) bool {
+	if r.Contains != "" && !strings.Contains(strings.ToLower(tx.Description), strings.ToLower(r.Contains)) {
+		return false
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(tx.Description) {
+		return false
+	}
+	if r.MinAmount != nil && tx.Amount.Cmp(*r.MinAmount) < 0 {
+		return false
+	}
+	if r.MaxAmount != nil && tx.Amount.Cmp(*r.MaxAmount) > 0 {
+		return false
+	}
+	if r.PaidBy != "" && !strings.EqualFold(r.PaidBy, tx.PaidBy) {
+		return false
+	}
+	if !r.From.IsZero() || !r.To.IsZero() {
+		date, err := tx.Date()
+		if err != nil {
+			return false
+		}
+		if (!r.From.IsZero() && date.Before(r.From)) || (!r.To.IsZero() && date.After(r.To)) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the rule by its line in the rules file and its category
+func (r Rule) String() string {
+	return fmt.Sprintf("rule on line %d (%s)", r.Line, r.Category)
+}
+
+// RuleSet is a list of rules, ordered from highest to lowest priority
+type RuleSet []Rule
+
+// NewRuleSet orders the rules by priority. Rules with the same priority keep their order
+func NewRuleSet(rules []Rule) RuleSet {
+	ruleSet := slices.Clone(rules)
+	slices.SortStableFunc(ruleSet, func(a, b Rule) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	return ruleSet
+}
+
+// ReadRulesFromFile reads a CSV rules file. The header names the columns, of which only Category is required:
+// Priority,Category,Contains,Pattern,MinAmount,MaxAmount,PaidBy,From,To
+func ReadRulesFromFile(path string) (RuleSet, error) {
+	var rules []Rule
+	err := csvfile.Read(path, []string{"Category"}, func(row csvfile.Row) error {
+		rule, err := parseRule(row)
+		if err != nil {
+			return err
+		}
+		rule.Line = row.Line
+		rules = append(rules, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file: %w", err)
+	}
+	return NewRuleSet(rules), nil
+}
+
+func parseRule(row csvfile.Row) (Rule, error) {
+	rule := Rule{Category: row.Get("Category"), Contains: row.Get("Contains"), PaidBy: row.Get("PaidBy")}
+	if rule.Category == "" {
+		return Rule{}, fmt.Errorf("missing category")
+	}
+	var err error
+	if rule.Priority, err = row.Int("Priority"); err != nil {
+		return Rule{}, err
+	}
+	if pattern := row.Get("Pattern"); pattern != "" {
+		if rule.Pattern, err = regexp.Compile(pattern); err != nil {
+			return Rule{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if rule.MinAmount, err = parseAmount(row.Get("MinAmount")); err != nil {
+		return Rule{}, fmt.Errorf("invalid minimum amount: %w", err)
+	}
+	if rule.MaxAmount, err = parseAmount(row.Get("MaxAmount")); err != nil {
+		return Rule{}, fmt.Errorf("invalid maximum amount: %w", err)
+	}
+	if rule.From, err = row.Date("From"); err != nil {
+		return Rule{}, err
+	}
+	if rule.To, err = row.Date("To"); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+func parseAmount(value string) (*currency.Euro, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	euros := currency.NewEuro(amount)
+	return &euros, nil
+}
+
+// Match returns the highest priority rule which matches the transaction, if any
+func (rs RuleSet) Match(tx transaction.PayerTransaction) (Rule, bool) {
+	for _, rule := range rs {
+		if rule.Matches(tx) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Result records which rule, if any, categorised a transaction
+type Result struct {
+	// Transaction is the transaction as it was before being categorised
+	Transaction transaction.PayerTransaction
+	Rule        Rule
+	Matched     bool
+}
+
+// String describes the transaction and the rule which matched it
+func (r Result) String() string {
+	description := fmt.Sprintf("%s,%s,%s", r.Transaction.Time, r.Transaction.Amount.String(), r.Transaction.Description)
+	if !r.Matched {
+		return description + " -> uncategorised"
+	}
+	return fmt.Sprintf("%s -> %s (%s)", description, r.Rule.Category, r.Rule.String())
+}
+
+// Apply categorises the transactions, setting the category of each transaction matched by a rule to the rule's
+// category. It returns the categorised transactions along with which rule matched each transaction
+func (rs RuleSet) Apply(transactions []transaction.PayerTransaction) ([]transaction.PayerTransaction, []Result) {
+	categorised := make([]transaction.PayerTransaction, 0, len(transactions))
+	results := make([]Result, 0, len(transactions))
+	for _, tx := range transactions {
+		rule, ok := rs.Match(tx)
+		results = append(results, Result{Transaction: tx, Rule: rule, Matched: ok})
+		if ok {
+			tx.Category = rule.Category
+		}
+		categorised = append(categorised, tx)
+	}
+	return categorised, results
+}
+
+// Uncategorised returns the transactions which no rule matched
+func Uncategorised(results []Result) []transaction.PayerTransaction {
+	var uncategorised []transaction.PayerTransaction
+	for _, result := range results {
+		if !result.Matched {
+			uncategorised = append(uncategorised, result.Transaction)
+		}
+	}
+	return uncategorised
+}
@@ -0,0 +1,66 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/rules"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func TestReadRulesFromFileOrdersByPriority(t *testing.T) {
+	ruleSet, err := rules.ReadRulesFromFile("../testdata/rules.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Rent", "Takeout", "Groceries", "Shopping"}
+	if len(ruleSet) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d", len(expected), len(ruleSet))
+	}
+	for idx, category := range expected {
+		if ruleSet[idx].Category != category {
+			t.Errorf("Expected rule %d to be %s, got %s", idx, category, ruleSet[idx].Category)
+		}
+	}
+	if ruleSet[0].Line != 4 {
+		t.Errorf("Expected Rent rule on line 4, got %d", ruleSet[0].Line)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ruleSet, err := rules.ReadRulesFromFile("../testdata/rules.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions, _, err := report.ReadTransactionsFromFile("../testdata/bankexport.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	categorised, results := ruleSet.Apply(transactions)
+	expected := []string{"Groceries", "Takeout", "Rent", "Shopping", "", ""}
+	for idx, category := range expected {
+		if categorised[idx].Category != category {
+			t.Errorf("Expected %s to be categorised as %q, got %q", transactions[idx].Description, category, categorised[idx].Category)
+		}
+		if categorised[idx].Description != transactions[idx].Description {
+			t.Errorf("Expected the description %s to be kept, got %s", transactions[idx].Description, categorised[idx].Description)
+		}
+	}
+	uncategorised := rules.Uncategorised(results)
+	if len(uncategorised) != 2 || uncategorised[0].Description != "Bakery" {
+		t.Errorf("Expected Bakery and Salary to be uncategorised, got %v", uncategorised)
+	}
+}
+
+func TestRuleMatchesPaidBy(t *testing.T) {
+	rule := rules.Rule{Category: "Allowance", PaidBy: "joe"}
+	tx := transaction.PayerTransaction{BasicTransaction: transaction.BasicTransaction{Amount: currency.NewEuro(-10), Description: "Cinema"}, PaidBy: "Joe"}
+	if !rule.Matches(tx) {
+		t.Errorf("Expected %v to match %v", rule, tx)
+	}
+	tx.PaidBy = "Charles"
+	if rule.Matches(tx) {
+		t.Errorf("Expected %v not to match %v", rule, tx)
+	}
+}
@@ -0,0 +1,187 @@
+package subscription
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/text"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Cadence is how often a subscription charges
+type Cadence struct {
+	Name string
+	// MinDays and MaxDays bound the number of days between two charges
+	MinDays, MaxDays int
+	// Months and Days are added to the last charge to get the next expected charge
+	Months, Days int
+	PerYear      int
+}
+
+var (
+	Weekly      = Cadence{Name: "weekly", MinDays: 6, MaxDays: 8, Days: 7, PerYear: 52}
+	Fortnightly = Cadence{Name: "fortnightly", MinDays: 12, MaxDays: 16, Days: 14, PerYear: 26}
+	Monthly     = Cadence{Name: "monthly", MinDays: 26, MaxDays: 35, Months: 1, PerYear: 12}
+	Quarterly   = Cadence{Name: "quarterly", MinDays: 84, MaxDays: 98, Months: 3, PerYear: 4}
+	Yearly      = Cadence{Name: "yearly", MinDays: 350, MaxDays: 380, Months: 12, PerYear: 1}
+	// Cadences are the cadences which are detected, from most to least frequent
+	Cadences = []Cadence{Weekly, Fortnightly, Monthly, Quarterly, Yearly}
+)
+
+// MinCharges is the number of charges needed to detect a subscription
+const MinCharges = 3
+
+// PriceChange is a charge whose amount differs from the charge before it
+type PriceChange struct {
+	Date     time.Time
+	From, To currency.Euro
+}
+
+// String describes the old and new price
+func (c PriceChange) String() string {
+	return fmt.Sprintf("price changed from %s to %s on %s", c.From.String(), c.To.String(), c.Date.Format(time.DateOnly))
+}
+
+// Subscription is a series of expenses to the same payee which repeat at a regular interval with a stable amount
+type Subscription struct {
+	Name    string
+	Cadence Cadence
+	Charges []transaction.BasicTransaction
+	// Amount is the latest charge
+	Amount       currency.Euro
+	LastDate     time.Time
+	NextExpected time.Time
+	PriceChanges []PriceChange
+}
+
+// AnnualCost returns the latest charge multiplied by the number of charges in a year
+func (s Subscription) AnnualCost() currency.Euro {
+	return currency.NewEuroFromCents(s.Amount.Cents() * int64(s.Cadence.PerYear))
+}
+
+// String describes the cadence, next expected charge, annual cost and price changes of the subscription
+func (s Subscription) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("%s: %s %s, next expected %s, %s per year\n", s.Name, s.Amount.String(), s.Cadence.Name, s.NextExpected.Format(time.DateOnly), s.AnnualCost().String()))
+	for _, change := range s.PriceChanges {
+		str.WriteString("  " + change.String() + "\n")
+	}
+	return str.String()
+}
+
+// charge is a dated expense
+type charge struct {
+	tx   transaction.BasicTransaction
+	date time.Time
+}
+
+// Detect finds the subscriptions among the transactions. Expenses are grouped by their payee, or their description if
+// they have no payee, ignoring case, numbers and punctuation, and names which start with another name are grouped
+// together. A group is a subscription if it has at least MinCharges charges, every interval between them fits one
+// cadence, and at least half of the charges cost the same as the charge before them. Subscriptions are sorted by
+// name
+func Detect(transactions []transaction.BasicTransaction) []Subscription {
+	groups := make(map[string][]charge)
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || !tx.IsIncomeOrExpense() || tx.Amount.Cmp(currency.NewEuro(0.0)) >= 0 {
+			continue
+		}
+		name := tx.Payee
+		if name == "" {
+			name = tx.Description
+		}
+		if key := text.Normalize(name); key != "" {
+			groups[key] = append(groups[key], charge{tx: tx, date: date})
+		}
+	}
+	var subscriptions []Subscription
+	for _, charges := range mergeSimilar(groups) {
+		if subscription, ok := detectSubscription(charges); ok {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b Subscription) int { return strings.Compare(a.Name, b.Name) })
+	return subscriptions
+}
+
+// mergeSimilar merges each group into the group with the shortest name it starts with, as whole words, so that
+// "netflix com" joins "netflix" but "applebees" stays apart from "apple"
+func mergeSimilar(groups map[string][]charge) [][]charge {
+	var merged [][]charge
+	var prefix string
+	for _, key := range slices.Sorted(maps.Keys(groups)) {
+		if prefix != "" && (key == prefix || strings.HasPrefix(key, prefix+" ")) {
+			merged[len(merged)-1] = append(merged[len(merged)-1], groups[key]...)
+			continue
+		}
+		prefix = key
+		merged = append(merged, groups[key])
+	}
+	return merged
+}
+
+// detectSubscription checks whether the charges are regular and stable enough to be a subscription
+func detectSubscription(charges []charge) (Subscription, bool) {
+	if len(charges) < MinCharges {
+		return Subscription{}, false
+	}
+	slices.SortStableFunc(charges, func(a, b charge) int { return a.date.Compare(b.date) })
+	cadence, ok := detectCadence(charges)
+	if !ok {
+		return Subscription{}, false
+	}
+	last := charges[len(charges)-1]
+	subscription := Subscription{Name: last.tx.Payee, Cadence: cadence, Amount: last.tx.Amount, LastDate: last.date, NextExpected: last.date.AddDate(0, cadence.Months, cadence.Days)}
+	if subscription.Name == "" {
+		subscription.Name = last.tx.Description
+	}
+	for idx, c := range charges {
+		subscription.Charges = append(subscription.Charges, c.tx)
+		if idx > 0 && c.tx.Amount.Cmp(charges[idx-1].tx.Amount) != 0 {
+			subscription.PriceChanges = append(subscription.PriceChanges, PriceChange{Date: c.date, From: charges[idx-1].tx.Amount, To: c.tx.Amount})
+		}
+	}
+	if 2*len(subscription.PriceChanges) > len(charges)-1 {
+		return Subscription{}, false
+	}
+	return subscription, true
+}
+
+// detectCadence returns the cadence which every interval between the sorted charges fits
+func detectCadence(charges []charge) (Cadence, bool) {
+	for _, cadence := range Cadences {
+		regular := true
+		for idx := 1; idx < len(charges) && regular; idx++ {
+			days := int(math.Round(charges[idx].date.Sub(charges[idx-1].date).Hours() / 24))
+			regular = days >= cadence.MinDays && days <= cadence.MaxDays
+		}
+		if regular {
+			return cadence, true
+		}
+	}
+	return Cadence{}, false
+}
+
+// Report lists the detected subscriptions and their total annual cost
+type Report struct {
+	Subscriptions []Subscription
+}
+
+// String lists each subscription followed by the total annual cost
+func (r Report) String() string {
+	var str strings.Builder
+	str.WriteString("Subscriptions\n")
+	total := currency.NewEuro(0.0)
+	for _, subscription := range r.Subscriptions {
+		str.WriteString(subscription.String())
+		total = currency.AddEuros(total, subscription.AnnualCost())
+	}
+	str.WriteString(fmt.Sprintf("%d subscription(s) costing %s per year\n", len(r.Subscriptions), total.String()))
+	return str.String()
+}
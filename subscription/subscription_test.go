@@ -0,0 +1,87 @@
+package subscription_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/subscription"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readTransactions(t *testing.T) []transaction.BasicTransaction {
+	payerTransactions, _, err := report.ReadTransactionsFromFile("../testdata/subscriptions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transactions []transaction.BasicTransaction
+	for _, tx := range payerTransactions {
+		transactions = append(transactions, tx.BasicTransaction)
+	}
+	return transactions
+}
+
+func TestDetect(t *testing.T) {
+	subscriptions := subscription.Detect(readTransactions(t))
+	if len(subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %d: %v", len(subscriptions), subscriptions)
+	}
+	netflix, spotify := subscriptions[0], subscriptions[1]
+	if netflix.Name != "NETFLIX.COM 45678" || spotify.Name != "Spotify" {
+		t.Errorf("Expected Netflix and Spotify, got %s and %s", netflix.Name, spotify.Name)
+	}
+	if netflix.Cadence.Name != "monthly" || len(netflix.Charges) != 4 {
+		t.Errorf("Expected 4 monthly Netflix charges, got %d %s charges", len(netflix.Charges), netflix.Cadence.Name)
+	}
+	if next := netflix.NextExpected.Format(time.DateOnly); next != "2025-05-03" {
+		t.Errorf("Expected the next Netflix charge on 2025-05-03, got %s", next)
+	}
+	expected := currency.NewEuroFromCents(-15588)
+	if actual := netflix.AnnualCost(); actual.Cmp(expected) != 0 {
+		t.Errorf("Expected Netflix to cost %s per year, got %s", expected.String(), actual.String())
+	}
+	if len(netflix.PriceChanges) != 1 || netflix.PriceChanges[0].String() != "price changed from €-9.99 to €-12.99 on 2025-03-03" {
+		t.Errorf("Expected one Netflix price change, got %v", netflix.PriceChanges)
+	}
+	if len(spotify.PriceChanges) != 0 {
+		t.Errorf("Expected no Spotify price changes, got %v", spotify.PriceChanges)
+	}
+}
+
+func TestDetectIgnoresIrregularCharges(t *testing.T) {
+	transactions := []transaction.BasicTransaction{
+		{Time: "2025-01-01", Amount: currency.NewEuro(-10), Description: "Bakery"},
+		{Time: "2025-01-02", Amount: currency.NewEuro(-10), Description: "Bakery"},
+		{Time: "2025-02-15", Amount: currency.NewEuro(-10), Description: "Bakery"},
+		{Time: "2025-01-01", Amount: currency.NewEuro(-10), Description: "Phone"},
+		{Time: "2025-02-01", Amount: currency.NewEuro(-25), Description: "Phone"},
+		{Time: "2025-03-01", Amount: currency.NewEuro(-40), Description: "Phone"},
+	}
+	if subscriptions := subscription.Detect(transactions); len(subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions, got %v", subscriptions)
+	}
+}
+
+func TestReportString(t *testing.T) {
+	actual := subscription.Report{Subscriptions: subscription.Detect(readTransactions(t))}.String()
+	expected := "Subscriptions\nNETFLIX.COM 45678: €-12.99 monthly, next expected 2025-05-03, €-155.88 per year\n  price changed from €-9.99 to €-12.99 on 2025-03-03\nSpotify: €-4.99 monthly, next expected 2025-05-12, €-59.88 per year\n2 subscription(s) costing €-215.76 per year\n"
+	if actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestDetectKeepsNamesWhichOnlyShareAPrefixApart(t *testing.T) {
+	var transactions []transaction.BasicTransaction
+	for month := 1; month <= 4; month++ {
+		transactions = append(transactions,
+			transaction.BasicTransaction{Time: fmt.Sprintf("2025-%02d-01", month), Amount: currency.NewEuro(-0.99), Description: "Apple"},
+			transaction.BasicTransaction{Time: fmt.Sprintf("2025-%02d-10", month), Amount: currency.NewEuro(-25), Description: "Applebees"},
+		)
+	}
+	subscriptions := subscription.Detect(transactions)
+	if len(subscriptions) != 2 || subscriptions[0].Name != "Apple" || subscriptions[1].Name != "Applebees" {
+		t.Errorf("Expected Apple and Applebees as separate subscriptions, got %v", subscriptions)
+	}
+}
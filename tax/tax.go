@@ -0,0 +1,166 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/internal/csvfile"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Rule flags the transactions in a category, including its sub-categories, or with a tag as tax-relevant under a tax
+// code
+type Rule struct {
+	Category string
+	Tag      string
+	Code     string
+	// Name describes the tax code, e.g. "Home office"
+	Name string
+}
+
+// Matches reports whether the rule flags the transaction. Tags are compared ignoring case
+func (r Rule) Matches(tx transaction.BasicTransaction) bool {
+	if r.Category != "" {
+		category := tx.EffectiveCategory()
+		return category == r.Category || strings.HasPrefix(category, r.Category+report.CategorySeparator)
+	}
+	return slices.ContainsFunc(tx.Tags, func(tag string) bool { return strings.EqualFold(tag, r.Tag) })
+}
+
+// ReadRulesFromFile reads tax rules from a CSV file with the columns Code and either Category or Tag, and optionally
+// Name
+func ReadRulesFromFile(path string) ([]Rule, error) {
+	var rules []Rule
+	err := csvfile.Read(path, []string{"Code"}, func(row csvfile.Row) error {
+		rule := Rule{Category: row.Get("Category"), Tag: row.Get("Tag"), Code: row.Get("Code"), Name: row.Get("Name")}
+		if rule.Code == "" {
+			return fmt.Errorf("missing tax code")
+		}
+		if (rule.Category == "") == (rule.Tag == "") {
+			return fmt.Errorf("tax code %s needs either a category or a tag", rule.Code)
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	return rules, err
+}
+
+// Item is a tax-relevant transaction with the tax code of the rule which flagged it
+type Item struct {
+	transaction.BasicTransaction
+	Code string
+	Name string
+}
+
+// Deductible reports whether the item is an expense, or a refund netting against one, rather than a taxable income
+func (i Item) Deductible() bool {
+	return i.IsExpense()
+}
+
+// CodeTotal is the total amount of the items with a tax code
+type CodeTotal struct {
+	Code  string
+	Name  string
+	Total currency.Euro
+	Count int
+}
+
+// Summary groups the deductible expenses and taxable incomes of a year by tax code
+type Summary struct {
+	Year       int
+	Items      []Item
+	Deductible []CodeTotal
+	Taxable    []CodeTotal
+}
+
+// NewSummary flags the transactions of the year with the first rule which matches them. Transfers, investments and
+// transactions whose time is not a date are left out
+func NewSummary(rules []Rule, transactions []transaction.BasicTransaction, year int) Summary {
+	summary := Summary{Year: year}
+	deductible := make(map[string]*CodeTotal)
+	taxable := make(map[string]*CodeTotal)
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || date.Year() != year || !tx.IsIncomeOrExpense() || tx.Amount.Cents() == 0 {
+			continue
+		}
+		idx := slices.IndexFunc(rules, func(r Rule) bool { return r.Matches(tx) })
+		if idx < 0 {
+			continue
+		}
+		item := Item{BasicTransaction: tx, Code: rules[idx].Code, Name: rules[idx].Name}
+		summary.Items = append(summary.Items, item)
+		totals := taxable
+		if item.Deductible() {
+			totals = deductible
+		}
+		if totals[item.Code] == nil {
+			totals[item.Code] = &CodeTotal{Code: item.Code, Name: item.Name}
+		}
+		totals[item.Code].Total = currency.AddEuros(totals[item.Code].Total, item.Amount)
+		totals[item.Code].Count++
+	}
+	slices.SortStableFunc(summary.Items, func(a, b Item) int { return strings.Compare(a.Time, b.Time) })
+	summary.Deductible = sortedTotals(deductible)
+	summary.Taxable = sortedTotals(taxable)
+	return summary
+}
+
+// sortedTotals returns the totals sorted by tax code
+func sortedTotals(totals map[string]*CodeTotal) []CodeTotal {
+	var sorted []CodeTotal
+	for _, code := range slices.Sorted(maps.Keys(totals)) {
+		sorted = append(sorted, *totals[code])
+	}
+	return sorted
+}
+
+// String lists the deductible expenses and the taxable incomes per tax code, with their totals
+func (s Summary) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("Tax Summary for %d\n", s.Year))
+	writeTotals(&str, "Deductible Expenses", s.Deductible)
+	writeTotals(&str, "Taxable Incomes", s.Taxable)
+	return str.String()
+}
+
+// writeTotals writes a section of totals per tax code followed by their sum
+func writeTotals(str *strings.Builder, title string, totals []CodeTotal) {
+	str.WriteString(title + "\n")
+	sum := currency.NewEuro(0.0)
+	for _, total := range totals {
+		str.WriteString(fmt.Sprintf("%s %s: %s (%d transaction(s))\n", total.Code, total.Name, total.Total.String(), total.Count))
+		sum = currency.AddEuros(sum, total.Total)
+	}
+	str.WriteString(fmt.Sprintf("Total: %s\n", sum.String()))
+}
+
+// WriteCSV writes every tax-relevant transaction for the tax advisor, with the columns
+// Date,Code,Tax Category,Type,Amount,Description,Category,Tags,Notes,ID, where the type is deductible or taxable
+func (s Summary) WriteCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write([]string{"Date", "Code", "Tax Category", "Type", "Amount", "Description", "Category", "Tags", "Notes", "ID"}); err != nil {
+		return fmt.Errorf("error writing tax export: %w", err)
+	}
+	for _, item := range s.Items {
+		kind := "taxable"
+		if item.Deductible() {
+			kind = "deductible"
+		}
+		record := []string{item.Time, item.Code, item.Name, kind, fmt.Sprintf("%.2f", float64(item.Amount.Cents())/100), item.Description, item.Category, strings.Join(item.Tags, report.TagSeparator), item.Notes, item.ID}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("error writing tax export: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error writing tax export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tax_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/tax"
+	"github.com/kevslinger/budget/transaction"
+)
+
+func readSummary(t *testing.T, year int) tax.Summary {
+	rules, err := tax.ReadRulesFromFile("../testdata/taxcodes.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := report.ReadDefaultBudgetReportFromFile("tax", "../testdata/taxtransactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tax.NewSummary(rules, r.Transactions(), year)
+}
+
+func TestSummary(t *testing.T) {
+	expected := "Tax Summary for 2025\n" +
+		"Deductible Expenses\n" +
+		"DON Donations: €-50.00 (1 transaction(s))\n" +
+		"MED Medical expenses: €-120.00 (1 transaction(s))\n" +
+		"WK Work equipment: €-1225.00 (2 transaction(s))\n" +
+		"Total: €-1395.00\n" +
+		"Taxable Incomes\n" +
+		"INC Employment income: €3000.00 (1 transaction(s))\n" +
+		"Total: €3000.00\n"
+	if actual := readSummary(t, 2025).String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	w := new(bytes.Buffer)
+	if err := readSummary(t, 2024).WriteCSV(w); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Date,Code,Tax Category,Type,Amount,Description,Category,Tags,Notes,ID\n" +
+		"2024-12-20,WK,Work equipment,deductible,-300.00,Old laptop,Work,,,\n"
+	if w.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, w.String())
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	rule := tax.Rule{Category: "Work", Code: "WK"}
+	if !rule.Matches(transaction.BasicTransaction{Category: "Work:Books"}) || rule.Matches(transaction.BasicTransaction{Category: "Workshop"}) {
+		t.Error("Expected the rule to match sub-categories only")
+	}
+	tagRule := tax.Rule{Tag: "donation", Code: "DON"}
+	if !tagRule.Matches(transaction.BasicTransaction{Tags: []string{"Donation"}}) {
+		t.Error("Expected the tag to match ignoring case")
+	}
+}
@@ -1,18 +1,130 @@
 package transaction
 
-import "github.com/kevslinger/budget/currency"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+)
+
+// TimeLayouts are the date formats which ParseTime understands, tried in order
+var TimeLayouts = []string{"2006-01-02", "02.01.2006", "2006/01/02"}
+
+// Kind distinguishes transactions which are not ordinary incomes or expenses
+type Kind string
+
+const (
+	// KindTransfer moves money between two of the user's own accounts, so it is neither an income nor an expense
+	KindTransfer Kind = "transfer"
+	// KindInvestment buys or sells investments, such as paying into a brokerage account, so it is saved rather than
+	// spent
+	KindInvestment Kind = "investment"
+)
+
+// Kinds lists every kind of transaction other than ordinary incomes and expenses
+var Kinds = []Kind{KindTransfer, KindInvestment}
+
+// ParseKind parses the kind of a transaction, where the empty string is an ordinary income or expense
+func ParseKind(value string) (Kind, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+	for _, kind := range Kinds {
+		if string(kind) == value {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown transaction kind %q", value)
+}
 
 // BasicTransaction contains the information to describe a single income or expense
 type BasicTransaction struct {
-	Amount      currency.Euro
-	Description string
-	Time        string
+	Amount currency.Euro `json:"amount"`
+	// Description is the free-text description of the transaction, such as the text on a bank statement
+	Description string `json:"description"`
+	Time        string `json:"time"`
+	// ID is an optional identifier assigned by the bank, such as an OFX FITID
+	ID string `json:"id,omitempty"`
+	// Category is used to aggregate transactions, e.g. "Groceries"
+	Category string `json:"category,omitempty"`
+	// Payee is the merchant or person the money was paid to or received from
+	Payee string   `json:"payee,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// Account is the name of the account the money was paid from or into, e.g. "Checking"
+	Account string `json:"account,omitempty"`
+	Kind    Kind   `json:"kind,omitempty"`
+	// Generated marks transactions created from a recurring definition, rather than entered or imported
+	Generated bool `json:"generated,omitempty"`
+	// VATRate is the VAT rate in percent included in the amount, e.g. 19, if the transaction has VAT
+	VATRate *float64 `json:"vatRate,omitempty"`
+	// VAT is the amount of VAT included in the amount, if the transaction has VAT
+	VAT *currency.Euro `json:"vat,omitempty"`
+	// Attachments reference receipts and other files in the attachment store by their content hash, e.g.
+	// "9f86d08...0f00a08.pdf"
+	Attachments []string `json:"attachments,omitempty"`
+	// Parent is the ID of the transaction this is a child line of, when one bank transaction is split across several
+	// categories or payers
+	Parent string `json:"parent,omitempty"`
+	// RefundOf marks a refund or reimbursement with the ID of the expense it pays back, or the category of the
+	// expenses it pays back, so it nets against them instead of counting as income
+	RefundOf string `json:"refundOf,omitempty"`
+	// Reimbursable marks an expense which someone else, such as an employer, is expected to pay back
+	Reimbursable bool `json:"reimbursable,omitempty"`
+	// Cleared marks a transaction which has been matched against a bank statement
+	Cleared bool `json:"cleared,omitempty"`
 }
 
 // PayerTransaction contains the information to describe a single income or expense, including who earned/paid
 type PayerTransaction struct {
-	Amount      currency.Euro
-	Description string
-	Time        string
-	PaidBy      string
+	BasicTransaction
+	PaidBy string `json:"paidBy"`
+}
+
+// EffectiveCategory returns the transaction's category, falling back to its description for transactions
+// which have not been categorised, where the description has traditionally doubled as the category
+func (t BasicTransaction) EffectiveCategory() string {
+	if t.Category != "" {
+		return t.Category
+	}
+	return t.Description
+}
+
+// IsIncomeOrExpense reports whether the transaction is an ordinary income or expense, rather than a transfer or an
+// investment
+func (t BasicTransaction) IsIncomeOrExpense() bool {
+	return t.Kind == ""
+}
+
+// IsRefund reports whether the transaction pays back an expense, such as a return or an employer reimbursement
+func (t BasicTransaction) IsRefund() bool {
+	return t.IsIncomeOrExpense() && t.RefundOf != ""
+}
+
+// IsIncome reports whether the transaction counts as income: money received which is not a refund
+func (t BasicTransaction) IsIncome() bool {
+	return t.IsIncomeOrExpense() && t.Amount.Cents() > 0 && t.RefundOf == ""
+}
+
+// IsExpense reports whether the transaction counts towards the expenses: money spent, or a refund which nets against
+// the money spent
+func (t BasicTransaction) IsExpense() bool {
+	return t.IsIncomeOrExpense() && (t.Amount.Cents() < 0 || t.RefundOf != "")
+}
+
+// Date parses the transaction's Time as a calendar date
+func (t BasicTransaction) Date() (time.Time, error) {
+	return ParseTime(t.Time)
+}
+
+// ParseTime parses a transaction time in one of the TimeLayouts, returning an error if none of them match
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range TimeLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q, expected a date such as 2025-01-31", value)
 }
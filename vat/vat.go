@@ -0,0 +1,145 @@
+package vat
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/transaction"
+)
+
+// Breakdown separates an amount into its net amount and the VAT included in it
+type Breakdown struct {
+	Gross currency.Euro
+	Net   currency.Euro
+	VAT   currency.Euro
+	// Rate is the VAT rate in percent, e.g. 19
+	Rate float64
+}
+
+// rateBasisPoints converts a rate in percent to hundredths of a percent, so VAT can be computed in whole numbers
+func rateBasisPoints(rate float64) int64 {
+	return int64(math.Round(rate * 100))
+}
+
+// divideRounded divides a by b, rounding halves away from zero like a till does
+func divideRounded(a, b int64) int64 {
+	if (a < 0) != (b < 0) {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
+
+// FromGross computes the VAT included in a gross amount at a rate, rounded to the nearest cent
+func FromGross(gross currency.Euro, rate float64) Breakdown {
+	bp := rateBasisPoints(rate)
+	vat := currency.NewEuroFromCents(divideRounded(gross.Cents()*bp, 10000+bp))
+	return Breakdown{Gross: gross, Net: currency.SubtractEuros(gross, vat), VAT: vat, Rate: rate}
+}
+
+// FromNet computes the VAT to add to a net amount at a rate, rounded to the nearest cent
+func FromNet(net currency.Euro, rate float64) Breakdown {
+	vat := currency.NewEuroFromCents(divideRounded(net.Cents()*rateBasisPoints(rate), 10000))
+	return Breakdown{Gross: currency.AddEuros(net, vat), Net: net, VAT: vat, Rate: rate}
+}
+
+// FromVAT computes the rate of the VAT included in a gross amount, rounded to two decimal places
+func FromVAT(gross, vat currency.Euro) Breakdown {
+	net := currency.SubtractEuros(gross, vat)
+	rate := 0.0
+	if net.Cents() != 0 {
+		rate = math.Round(float64(vat.Cents())/float64(net.Cents())*10000) / 100
+	}
+	return Breakdown{Gross: gross, Net: net, VAT: vat, Rate: rate}
+}
+
+// Of returns the breakdown of a transaction's amount, which is the gross amount, computing the VAT from the rate or
+// the rate from the VAT when only one of them is given. The VAT takes the sign of the amount, so the VAT of an expense
+// may be given as a positive amount. It returns false if the transaction has neither
+func Of(tx transaction.BasicTransaction) (Breakdown, bool) {
+	switch {
+	case tx.VAT != nil && tx.VATRate != nil:
+		vat := withSignOf(*tx.VAT, tx.Amount)
+		return Breakdown{Gross: tx.Amount, Net: currency.SubtractEuros(tx.Amount, vat), VAT: vat, Rate: *tx.VATRate}, true
+	case tx.VAT != nil:
+		return FromVAT(tx.Amount, withSignOf(*tx.VAT, tx.Amount)), true
+	case tx.VATRate != nil:
+		return FromGross(tx.Amount, *tx.VATRate), true
+	}
+	return Breakdown{}, false
+}
+
+// withSignOf returns the amount with the same sign as another amount
+func withSignOf(amount, other currency.Euro) currency.Euro {
+	if (amount.Cents() < 0) != (other.Cents() < 0) {
+		return currency.NewEuroFromCents(-amount.Cents())
+	}
+	return amount
+}
+
+// Quarter returns the quarter, from 1 to 4, of a date
+func Quarter(date time.Time) int {
+	return (int(date.Month())-1)/3 + 1
+}
+
+// Line is a transaction with VAT in a quarterly report
+type Line struct {
+	transaction.BasicTransaction
+	Breakdown Breakdown
+}
+
+// Report is the VAT return of a quarter: the VAT collected on incomes, the VAT paid on expenses, and the difference
+// which is due to the tax office
+type Report struct {
+	Year    int
+	Quarter int
+	Lines   []Line
+	// Collected is the VAT included in the incomes
+	Collected currency.Euro
+	// Paid is the VAT included in the expenses, less the VAT of their refunds, as a positive amount
+	Paid currency.Euro
+}
+
+// NewReport collects the transactions with VAT in a quarter of a year. Transfers, investments and transactions whose
+// time is not a date are left out
+func NewReport(transactions []transaction.BasicTransaction, year, quarter int) Report {
+	r := Report{Year: year, Quarter: quarter}
+	for _, tx := range transactions {
+		date, err := tx.Date()
+		if err != nil || date.Year() != year || Quarter(date) != quarter || !tx.IsIncomeOrExpense() {
+			continue
+		}
+		breakdown, ok := Of(tx)
+		if !ok {
+			continue
+		}
+		r.Lines = append(r.Lines, Line{BasicTransaction: tx, Breakdown: breakdown})
+		if tx.IsIncome() {
+			r.Collected = currency.AddEuros(r.Collected, breakdown.VAT)
+		} else {
+			r.Paid = currency.SubtractEuros(r.Paid, breakdown.VAT)
+		}
+	}
+	return r
+}
+
+// Due returns the VAT collected minus the VAT paid, which is negative when the tax office owes a refund
+func (r Report) Due() currency.Euro {
+	return currency.SubtractEuros(r.Collected, r.Paid)
+}
+
+// String lists the transactions with their net amount and VAT, followed by the VAT collected, paid and due
+func (r Report) String() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("VAT Report for %d Q%d\n", r.Year, r.Quarter))
+	str.WriteString("Time,Description,Gross,Net,VAT Rate,VAT\n")
+	for _, line := range r.Lines {
+		str.WriteString(fmt.Sprintf("%s,%s,%s,%s,%g%%,%s\n", line.Time, line.Description, line.Breakdown.Gross.String(), line.Breakdown.Net.String(), line.Breakdown.Rate, line.Breakdown.VAT.String()))
+	}
+	str.WriteString(fmt.Sprintf("VAT Collected: %s\n", r.Collected.String()))
+	str.WriteString(fmt.Sprintf("VAT Paid: %s\n", r.Paid.String()))
+	str.WriteString(fmt.Sprintf("VAT Due: %s\n", r.Due().String()))
+	return str.String()
+}
@@ -0,0 +1,65 @@
+package vat_test
+
+import (
+	"testing"
+
+	"github.com/kevslinger/budget/currency"
+	"github.com/kevslinger/budget/report"
+	"github.com/kevslinger/budget/vat"
+)
+
+func TestFromGross(t *testing.T) {
+	tests := []struct {
+		gross       float64
+		rate        float64
+		expectedVAT int64
+	}{
+		{119, 19, 1900},
+		{-49.99, 19, -798},
+		{10.70, 7, 70},
+		// 0.07 * 19 / 119 is 0.011176..., and 0.10 * 19 / 119 is 0.015966...
+		{0.07, 19, 1},
+		{0.10, 19, 2},
+		{100, 0, 0},
+	}
+	for _, test := range tests {
+		breakdown := vat.FromGross(currency.NewEuro(test.gross), test.rate)
+		if breakdown.VAT.Cents() != test.expectedVAT {
+			t.Errorf("Expected %v at %v%% to include %d cents of VAT, got %d", test.gross, test.rate, test.expectedVAT, breakdown.VAT.Cents())
+		}
+		if currency.AddEuros(breakdown.Net, breakdown.VAT).Cmp(breakdown.Gross) != 0 {
+			t.Errorf("Expected the net amount and VAT to add up to the gross amount, got %+v", breakdown)
+		}
+	}
+}
+
+func TestFromNetAndFromVAT(t *testing.T) {
+	fromNet := vat.FromNet(currency.NewEuro(42.01), 19)
+	if fromNet.VAT.Cents() != 798 || fromNet.Gross.Cents() != 4999 {
+		t.Errorf("Expected €7.98 of VAT and a gross amount of €49.99, got %+v", fromNet)
+	}
+	fromVAT := vat.FromVAT(currency.NewEuro(49.99), currency.NewEuro(7.98))
+	if fromVAT.Rate != 19 || fromVAT.Net.Cents() != 4201 {
+		t.Errorf("Expected a rate of 19%% and a net amount of €42.01, got %+v", fromVAT)
+	}
+}
+
+func TestReport(t *testing.T) {
+	r, err := report.ReadDefaultBudgetReportFromFile("vat", "../testdata/vattransactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "VAT Report for 2025 Q2\n" +
+		"Time,Description,Gross,Net,VAT Rate,VAT\n" +
+		"2025-04-03,Invoice 2025-004,€2380.00,€2000.00,19%,€380.00\n" +
+		"2025-04-10,Office supplies,€-11.90,€-10.00,19%,€-1.90\n" +
+		"2025-04-15,Books,€-10.70,€-10.00,7%,€-0.70\n" +
+		"2025-05-02,Software licence,€-49.99,€-42.01,19%,€-7.98\n" +
+		"2025-06-30,Invoice 2025-005,€535.00,€500.00,7%,€35.00\n" +
+		"VAT Collected: €415.00\n" +
+		"VAT Paid: €10.58\n" +
+		"VAT Due: €404.42\n"
+	if actual := vat.NewReport(r.Transactions(), 2025, 2).String(); actual != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, actual)
+	}
+}